@@ -13,7 +13,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('/
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function closeAuction (ccp, wallet, user, auctionName) {
+async function closeAuction (ccp, wallet, user, auctionName, expectedVersion = 0) {
 	const gateway = new Gateway();
 	// connect using Discovery enabled
 
@@ -26,7 +26,7 @@ async function closeAuction (ccp, wallet, user, auctionName) {
 	const statefulTxn = contract.createTransaction('CloseAuction');
 
 	console.log('\n--> Submit Transaction: Close the auction');
-	await statefulTxn.submit(auctionName);
+	await statefulTxn.submit(auctionName, expectedVersion.toString());
 	console.log('*** Result: committed');
 
 	gateway.disconnect();
@@ -35,13 +35,14 @@ async function closeAuction (ccp, wallet, user, auctionName) {
 async function main () {
 	try {
 		if (process.argv.length < 5) {
-			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName`);
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName [expectedVersion]`);
 			process.exit(1);
 		}
 
 		const org = process.argv[2].toLowerCase();
 		const user = process.argv[3];
 		const auctionName = process.argv[4];
+		const expectedVersion = process.argv.length > 5 ? BigInt(process.argv[5]) : 0;
 		
 		let ccp = null;
 		let walletPath = null;
@@ -58,7 +59,7 @@ async function main () {
 			process.exit(1);
 		}
 		const wallet = await buildWallet(Wallets, walletPath);
-		await closeAuction(ccp, wallet, user, auctionName);
+		await closeAuction(ccp, wallet, user, auctionName, expectedVersion);
 	}
 	catch (error) {
 		console.error(`******** FAILED to run the application: ${error}`);
@@ -13,7 +13,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('/
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function createAuction (ccp, wallet, user, auctionName, directBuyPrice) {
+async function createAuction (ccp, wallet, user, auctionName, directBuyPrice, options = {}) {
 	const gateway = new Gateway();
 	// connect using Discovery enabled
 
@@ -26,7 +26,7 @@ async function createAuction (ccp, wallet, user, auctionName, directBuyPrice) {
 	const statefulTxn = contract.createTransaction('CreateAuction');
 
 	console.log('\n--> Submit Transaction: Propose a new auction');
-	await statefulTxn.submit(auctionName, directBuyPrice);
+	await statefulTxn.submit(auctionName, directBuyPrice, JSON.stringify(options));
 	console.log('*** Result: committed');
 
 	gateway.disconnect();
@@ -35,7 +35,7 @@ async function createAuction (ccp, wallet, user, auctionName, directBuyPrice) {
 async function main () {
 	try {
 		if (process.argv.length < 5) {
-			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName [directBuyPrice]`);
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName [directBuyPrice] [options]`);
 			process.exit(1);
 		}
 
@@ -43,6 +43,7 @@ async function main () {
 		const user = process.argv[3];
 		const auctionName = process.argv[4];
 		const directBuyPrice = process.argv[5] ?? 0;
+		const options = JSON.parse(process.argv[6] ?? '{}');
 		
 		let ccp = null;
 		let walletPath = null;
@@ -59,7 +60,7 @@ async function main () {
 			process.exit(1);
 		}
 		const wallet = await buildWallet(Wallets, walletPath);
-		await createAuction(ccp, wallet, user, auctionName, directBuyPrice);
+		await createAuction(ccp, wallet, user, auctionName, directBuyPrice, options);
 	}
 	catch (error) {
 		console.error(`******** FAILED to run the application: ${error}`);
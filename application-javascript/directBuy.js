@@ -14,7 +14,7 @@ const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
 
-async function directBuy (ccp, wallet, user, auctionName, price) {
+async function directBuy (ccp, wallet, user, auctionName, price, expectedVersion = 0) {
 	const gateway = new Gateway();
 	// connect using Discovery enabled
 
@@ -30,7 +30,7 @@ async function directBuy (ccp, wallet, user, auctionName, price) {
 	const statefulTxn = contract.createTransaction('DirectBuy');
 
 	console.log('\n--> Submit Transaction: Direct Buy');
-	await statefulTxn.submit(auctionName, price);
+	await statefulTxn.submit(auctionName, price, expectedVersion.toString());
 	console.log('*** Result: committed');
 
 	gateway.disconnect();
@@ -39,7 +39,7 @@ async function directBuy (ccp, wallet, user, auctionName, price) {
 async function main () {
 	try {
 		if (process.argv.length < 6) {
-			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName price`);
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName price [expectedVersion]`);
 			process.exit(1);
 		}
 
@@ -47,6 +47,7 @@ async function main () {
 		const user = process.argv[3];
 		const auctionName = process.argv[4];
 		const price = BigInt(process.argv[5]);
+		const expectedVersion = process.argv.length > 6 ? BigInt(process.argv[6]) : 0;
 		
 		let ccp = null;
 		let walletPath = null;
@@ -63,7 +64,7 @@ async function main () {
 			process.exit(1);
 		}
 		const wallet = await buildWallet(Wallets, walletPath);
-		await directBuy(ccp, wallet, user, auctionName, price);
+		await directBuy(ccp, wallet, user, auctionName, price, expectedVersion);
 	}
 	catch (error) {
 		console.error(`******** FAILED to run the application: ${error}`);
@@ -14,7 +14,7 @@ const { X509Certificate } = require('crypto');
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function endAuction (ccp, wallet, user, auctionName) {
+async function endAuction (ccp, wallet, user, auctionName, expectedVersion = 0) {
 	const gateway = new Gateway();
 	// connect using Discovery enabled
 
@@ -27,7 +27,7 @@ async function endAuction (ccp, wallet, user, auctionName) {
 	const statefulTxn = contract.createTransaction('EndAuction');
 
 	console.log('\n--> Submit Transaction: End the auction');
-	await statefulTxn.submit(auctionName);
+	await statefulTxn.submit(auctionName, expectedVersion.toString());
 	console.log('*** Result: committed');
 
 	gateway.disconnect();
@@ -36,13 +36,14 @@ async function endAuction (ccp, wallet, user, auctionName) {
 async function main () {
 	try {
 		if (process.argv.length < 5) {
-			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName`);
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName [expectedVersion]`);
 			process.exit(1);
 		}
 
 		const org = process.argv[2].toLowerCase();
 		const user = process.argv[3];
 		const auctionName = process.argv[4];
+		const expectedVersion = process.argv.length > 5 ? BigInt(process.argv[5]) : 0;
 
 		let contract = null;
 		let gateway = null;
@@ -76,11 +77,13 @@ async function main () {
 			const auctionKey = `auction ${auctionName}`;
 			let resultPromiseResolver = null;
 			let auctionResult = null
+			let anonymousIDs = false;
 			contractListener = (event) => {
 				if (event.eventName == auctionKey) {
 					const auctionSummary = JSON.parse(event.payload.toString("utf8"));
 					console.log(`Auction status: ${auctionSummary.status}`);
 					auctionResult = auctionSummary.result;
+					anonymousIDs = auctionSummary.anonymousIDs === true;
 					if (resultPromiseResolver !== null) {
 						resultPromiseResolver(auctionResult);
 					}
@@ -89,7 +92,7 @@ async function main () {
 			contract.addContractListener(contractListener);
 
 			// End the auction
-			await endAuction(ccp, wallet, user, auctionName);
+			await endAuction(ccp, wallet, user, auctionName, expectedVersion);
 
 			// Wait for the auction to end
 			await new Promise((resolve, reject) => {
@@ -103,6 +106,12 @@ async function main () {
 
 			const winner = Buffer.from(auctionResult.winner, 'base64');
 			const hammerPrice = BigInt(auctionResult.hammerPrice);
+			if (anonymousIDs) {
+				// The event only contains anonymous IDs instead of certificates
+				console.log(`The anonymous ID of the auction winner is: ${winner.toString('hex')}`);
+				console.log(`The hammer price is: ${hammerPrice}`);
+				return;
+			}
 			const winnerCert = new X509Certificate(winner);
 			const winnerSubject = winnerCert.subject;
 
@@ -110,6 +119,12 @@ async function main () {
 			console.log(`The hammer price is: ${hammerPrice}`);
 			console.log("Full X.509 certificate of the winner:");
 			console.log(winnerCert);
+			if (auctionResult.winners.length > 1) {
+				console.log("All winners, each won one unit:");
+				for (const unitWinner of auctionResult.winners) {
+					console.log(new X509Certificate(Buffer.from(unitWinner, 'base64')).subject);
+				}
+			}
 		}
 		finally {
 			if (contract !== null && contractListener !== null) {
@@ -0,0 +1,74 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const fs = require('fs');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('/home/fabric-user/fabric-samples/test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function inviteBidder (ccp, wallet, user, auctionName, bidderPem) {
+	const gateway = new Gateway();
+	// connect using Discovery enabled
+
+	await gateway.connect(ccp,
+		{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+	const network = await gateway.getNetwork(myChannel);
+	const contract = network.getContract(myChaincodeName);
+
+	const statefulTxn = contract.createTransaction('InviteBidder');
+
+	console.log('\n--> Submit Transaction: Invite a bidder');
+	await statefulTxn.submit(auctionName, bidderPem);
+	console.log('*** Result: committed');
+
+	gateway.disconnect();
+}
+
+async function main () {
+	try {
+		if (process.argv.length < 6) {
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName bidderCertFile`);
+			process.exit(1);
+		}
+
+		const org = process.argv[2].toLowerCase();
+		const user = process.argv[3];
+		const auctionName = process.argv[4];
+		const bidderPem = fs.readFileSync(process.argv[5], 'utf8');
+		
+		let ccp = null;
+		let walletPath = null;
+		if (org === 'org1') {
+			ccp = buildCCPOrg1();
+			walletPath = path.join(__dirname, 'wallet/org1');
+		}
+		else if (org === 'org2') {
+			ccp = buildCCPOrg2();
+			walletPath = path.join(__dirname, 'wallet/org2');
+		}
+		else {
+			console.error('Org must be org1 or org2 ...');
+			process.exit(1);
+		}
+		const wallet = await buildWallet(Wallets, walletPath);
+		await inviteBidder(ccp, wallet, user, auctionName, bidderPem);
+	}
+	catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+if (require.main === module) {
+	main();
+}
+
+module.exports = {inviteBidder};
@@ -0,0 +1,78 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('/home/fabric-user/fabric-samples/test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function queryAuction (ccp, wallet, user, transactionName, ...args) {
+	const gateway = new Gateway();
+	// connect using Discovery enabled
+
+	await gateway.connect(ccp,
+		{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+	const network = await gateway.getNetwork(myChannel);
+	const contract = network.getContract(myChaincodeName);
+
+	console.log(`\n--> Evaluate Transaction: ${transactionName}`);
+	const result = await contract.evaluateTransaction(transactionName, ...args);
+
+	gateway.disconnect();
+
+	return result.toString();
+}
+
+async function main () {
+	try {
+		if (process.argv.length < 5) {
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user transactionName [args...]`);
+			process.exit(1);
+		}
+
+		const org = process.argv[2].toLowerCase();
+		const user = process.argv[3];
+		const transactionName = process.argv[4];
+		const args = process.argv.slice(5);
+
+		let ccp = null;
+		let walletPath = null;
+		if (org === 'org1') {
+			ccp = buildCCPOrg1();
+			walletPath = path.join(__dirname, 'wallet/org1');
+		}
+		else if (org === 'org2') {
+			ccp = buildCCPOrg2();
+			walletPath = path.join(__dirname, 'wallet/org2');
+		}
+		else {
+			console.error('Org must be org1 or org2 ...');
+			process.exit(1);
+		}
+		const wallet = await buildWallet(Wallets, walletPath);
+		const result = await queryAuction(ccp, wallet, user, transactionName, ...args);
+		try {
+			console.log(`*** Result: ${prettyJSONString(result)}`);
+		}
+		catch (error) {
+			console.log(`*** Result: ${result}`);
+		}
+	}
+	catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+if (require.main === module) {
+	main();
+}
+
+module.exports = {queryAuction};
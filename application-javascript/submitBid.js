@@ -27,13 +27,24 @@ function hashBid(clientCert, bidPrice, salt) {
 	return shake.getHash("UINT8ARRAY", {outputLen: 512});
 }
 
-function generateSalt() {
-	let salt = new Uint8Array(64);
+function generateSalt(saltLength) {
+	let salt = new Uint8Array(saltLength);
 	getRandomValues(salt);
 	return salt;
 }
 
-async function submitBid (ccp, wallet, user, auctionName, bidPrice) {
+function leadingZeroBits(data) {
+	let count = 0;
+	for (const byte of data) {
+		if (byte !== 0) {
+			return count + Math.clz32(byte) - 24;
+		}
+		count += 8;
+	}
+	return count;
+}
+
+async function submitBid (ccp, wallet, user, auctionName, bidPrice, saltLength = 64, commitDifficulty = 0) {
 	const gateway = new Gateway();
 	// connect using Discovery enabled
 
@@ -50,8 +61,13 @@ async function submitBid (ccp, wallet, user, auctionName, bidPrice) {
 
 	const clientCert = new X509Certificate(clientID.credentials.certificate);
 
-	let salt = generateSalt();
+	// Choose salts until the hidden commit has as many leading zero bits as the auction requires
+	let salt = generateSalt(saltLength);
 	let bidHash = hashBid(clientCert, bidPrice, salt);
+	while (leadingZeroBits(bidHash) < commitDifficulty) {
+		salt = generateSalt(saltLength);
+		bidHash = hashBid(clientCert, bidPrice, salt);
+	}
 	let bidHashHex = uint8ArrayToHex(bidHash);
 
 	console.log(`Hidden Bid Hash: ${bidHashHex}`);
@@ -68,7 +84,7 @@ async function submitBid (ccp, wallet, user, auctionName, bidPrice) {
 async function main () {
 	try {
 		if (process.argv.length < 6) {
-			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName bidPrice`);
+			console.error(`Usage: ${process.argv[0]} ${process.argv[1]} org user auctionName bidPrice [saltLength] [commitDifficulty]`);
 			process.exit(1);
 		}
 
@@ -76,6 +92,8 @@ async function main () {
 		const user = process.argv[3];
 		const auctionName = process.argv[4];
 		const bidPrice = BigInt(process.argv[5]);
+		const saltLength = process.argv.length > 6 ? parseInt(process.argv[6]) : 64;
+		const commitDifficulty = process.argv.length > 7 ? parseInt(process.argv[7]) : 0;
 		
 		let ccp = null;
 		let walletPath = null;
@@ -92,7 +110,7 @@ async function main () {
 			process.exit(1);
 		}
 		const wallet = await buildWallet(Wallets, walletPath);
-		const salt = await submitBid(ccp, wallet, user, auctionName, bidPrice);
+		const salt = await submitBid(ccp, wallet, user, auctionName, bidPrice, saltLength, commitDifficulty);
 		console.log(`Please save the salt:\n${arrayToHexString(salt)}`);
 	}
 	catch (error) {
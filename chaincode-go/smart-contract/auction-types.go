@@ -4,20 +4,195 @@ SPDX-License-Identifier: Apache-2.0
 
 package auction
 
-// enum possible status: open, closed, ended
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Error codes of AuctionError
+const (
+	ErrCodeNotFound            = "NOT_FOUND"
+	ErrCodeAlreadyExists       = "ALREADY_EXISTS"
+	ErrCodePermissionDenied    = "PERMISSION_DENIED"
+	ErrCodeInvalidStatus       = "INVALID_STATUS"
+	ErrCodeInvalidArgument     = "INVALID_ARGUMENT"
+	ErrCodeBidMismatch         = "BID_MISMATCH"
+	ErrCodeAlreadyRevealed     = "ALREADY_REVEALED"
+	ErrCodeInsufficientBalance = "INSUFFICIENT_BALANCE"
+	ErrCodeVersionConflict     = "VERSION_CONFLICT"
+)
+
+// AuctionError is an error which clients can distinguish by its code
+// Fabric only passes the error message to the client, so the message starts with the code, e.g. "NOT_FOUND: auction not found".
+type AuctionError struct {
+	Code    string
+	Message string
+}
+
+func (err *AuctionError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Code, err.Message)
+}
+
+// newAuctionError creates an AuctionError with a formatted message
+func newAuctionError(code string, format string, args ...interface{}) *AuctionError {
+	return &AuctionError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// wrapError adds context to the message of an error, but returns coded errors unchanged, so that their code stays at the start
+func wrapError(err error, context string) error {
+	if _, coded := err.(*AuctionError); coded {
+		return err
+	}
+	return fmt.Errorf("%s: %v", context, err)
+}
+
+// enum possible status: open, closed, ended, cancelled
 type AuctionStatus int
 
 const (
-	Open   AuctionStatus = iota // Buyers can send hidden bids or direct buy
-	Closed                      // Buyers opens bids
-	Ended                       // Auction is closed and winner is set
+	Open      AuctionStatus = iota // Buyers can send hidden bids or direct buy
+	Closed                         // Buyers opens bids
+	Ended                          // Auction is closed and winner is set
+	Cancelled                      // Auction was cancelled by the seller and removed from the world state
 )
 
+var auctionStatusNames = map[AuctionStatus]string{
+	Open:      "open",
+	Closed:    "closed",
+	Ended:     "ended",
+	Cancelled: "cancelled",
+}
+
+func (status AuctionStatus) String() string {
+	name, ok := auctionStatusNames[status]
+	if !ok {
+		return fmt.Sprintf("AuctionStatus(%d)", int(status))
+	}
+	return name
+}
+
+// IsTerminal returns true if an auction with this status cannot change anymore
+// New statuses must be added here if they are final.
+func (status AuctionStatus) IsTerminal() bool {
+	switch status {
+	case Ended, Cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalJSON encodes the status as a string, e.g. "open"
+func (status AuctionStatus) MarshalJSON() ([]byte, error) {
+	name, ok := auctionStatusNames[status]
+	if !ok {
+		return nil, fmt.Errorf("unknown auction status %d", int(status))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes the status from a string
+// The integer encoding is still accepted, so that auctions stored before the string encoding was introduced can be read.
+func (status *AuctionStatus) UnmarshalJSON(data []byte) error {
+	var number int
+	if errNumber := json.Unmarshal(data, &number); errNumber == nil {
+		if _, ok := auctionStatusNames[AuctionStatus(number)]; !ok {
+			return fmt.Errorf("unknown auction status %d", number)
+		}
+		*status = AuctionStatus(number)
+		return nil
+	}
+	var name string
+	if errName := json.Unmarshal(data, &name); errName != nil {
+		return fmt.Errorf("auction status must be a string or an integer: %v", errName)
+	}
+	for candidate, candidateName := range auctionStatusNames {
+		if name == candidateName {
+			*status = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown auction status %q", name)
+}
+
+// enum possible auction types: vickrey, first price, all-pay, dutch, english
+// The commit and reveal of bids is the same for the sealed-bid types, they only differ in what the bidders pay.
+// Dutch auctions have no bids, the first buyer who accepts the descending clock price wins.
+// English auctions have open bids, every bid must outbid the standing high bid.
+type AuctionType int
+
+const (
+	Vickrey    AuctionType = iota // The winners pay the highest losing bid
+	FirstPrice                    // The winner pays their own bid
+	AllPay                        // The highest bidder wins, but every bidder pays their own bid
+	Dutch                         // The first buyer who accepts the current clock price wins and pays it
+	English                       // The highest open bid wins and pays its price
+)
+
+var auctionTypeNames = map[AuctionType]string{
+	Vickrey:    "vickrey",
+	FirstPrice: "firstPrice",
+	AllPay:     "allPay",
+	Dutch:      "dutch",
+	English:    "english",
+}
+
+func (auctionType AuctionType) String() string {
+	name, ok := auctionTypeNames[auctionType]
+	if !ok {
+		return fmt.Sprintf("AuctionType(%d)", int(auctionType))
+	}
+	return name
+}
+
+// parseAuctionType gets the auction type from its name, the empty name means Vickrey
+func parseAuctionType(name string) (AuctionType, error) {
+	if name == "" {
+		return Vickrey, nil
+	}
+	for candidate, candidateName := range auctionTypeNames {
+		if name == candidateName {
+			return candidate, nil
+		}
+	}
+	return Vickrey, fmt.Errorf("unknown auction type %q", name)
+}
+
+// MarshalJSON encodes the auction type as a string, e.g. "vickrey"
+func (auctionType AuctionType) MarshalJSON() ([]byte, error) {
+	name, ok := auctionTypeNames[auctionType]
+	if !ok {
+		return nil, fmt.Errorf("unknown auction type %d", int(auctionType))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes the auction type from a string
+func (auctionType *AuctionType) UnmarshalJSON(data []byte) error {
+	var name string
+	if errName := json.Unmarshal(data, &name); errName != nil {
+		return fmt.Errorf("auction type must be a string: %v", errName)
+	}
+	parsedType, errParse := parseAuctionType(name)
+	if errParse != nil {
+		return errParse
+	}
+	*auctionType = parsedType
+	return nil
+}
+
 // Bid data
 type Bid struct {
+	ID           string `json:"id"`       // ID of the transaction which submitted the bid, it is part of the bid's world state key
 	Buyer        []byte `json:"buyer"`    // the certificate of the potential buyer
 	BidPrice     uint64 `json:"bidPrice"` // 0 means hidden, later set the actual bid price during reveal
 	HiddenCommit []byte `json:"hiddenCommit"`
+	Timestamp    int64  `json:"timestamp"`            // Unix time of the bid transaction (0 for bids stored before it was recorded)
+	Signature    []byte `json:"signature,omitempty"`  // Signature of the bidder over the hidden commit, only set for bids submitted with BidSigned
+	Ineligible   bool   `json:"ineligible,omitempty"` // Set if the revealed price is outside the auction's price range, such bids never win and are not counted
 	/*
 		HiddenCommit is the 64 byte SHAKE256 output of (clientCert, bidPrice, salt)
 		* clientCert is the X.509 client certificate in DER format
@@ -28,26 +203,287 @@ type Bid struct {
 }
 
 type Auction struct {
-	Name           string        `json:"name"`   // The auction name should be globally unique
-	Seller         []byte        `json:"seller"` // The seller who opened this auction
-	Status         AuctionStatus `json:"status"`
-	DirectBuyPrice uint64        `json:"directBuyPrice"` // A buyer can directly buy the item by paying at least this price (0 means disabled)
-	Bids           []Bid         `json:"bids"`
-	Winner         []byte        `json:"winner"`
-	HammerPrice    uint64        `json:"hammerPrice"`
+	Name                 string         `json:"name"`   // The auction name should be globally unique
+	Seller               []byte         `json:"seller"` // The seller who opened this auction
+	Status               AuctionStatus  `json:"status"`
+	Type                 AuctionType    `json:"type"`                // Auctions stored before the type was introduced are Vickrey auctions
+	DirectBuyPrice       uint64         `json:"directBuyPrice"`      // A buyer can directly buy the item by paying at least this price (0 means disabled)
+	PriceScale           uint8          `json:"priceScale"`          // Number of implied decimal places of all prices, e.g. 2 if prices are in cents
+	ItemRef              string         `json:"itemRef"`             // Optional reference to the sold item, only one unfinished auction can exist per item
+	HasReserve           bool           `json:"hasReserve"`          // If false, the auction has no reserve price
+	ReservePrice         uint64         `json:"reservePrice"`        // The minimum hammer price, only valid if HasReserve is set (a reserve of 0 is allowed)
+	EarliestClose        int64          `json:"earliestClose"`       // The seller commits to not closing the auction before this Unix time (0 means no commitment)
+	AllowedMSPs          []string       `json:"allowedMSPs"`         // Only clients from these MSPs may buy, an empty list allows everyone
+	InvitedBidders       [][]byte       `json:"invitedBidders"`      // Only these clients (DER certificates) may buy, an empty list allows everyone
+	ScrubLosersAfterEnd  bool           `json:"scrubLosersAfterEnd"` // If set, EndAuction removes the identity and hidden commit of the losing bids
+	MinSaltBytes         uint32         `json:"minSaltBytes"`        // Minimum salt length in bytes required by OpenBid (0 means the default of 64)
+	Quantity             uint64         `json:"quantity"`            // Number of identical units sold, each bidder can win at most one (0 means 1)
+	MinDeposit           uint64         `json:"minDeposit"`          // Amount which every bidder has to lock from their balance (0 means no deposit)
+	FeeBps               uint16         `json:"feeBps"`              // Platform fee in basis points of the payments to the seller (0 means no fee)
+	FeePayer             string         `json:"feePayer"`            // "seller" if the fee is deducted from the seller's proceeds, "buyer" if the buyers pay it on top
+	FeeRecipient         []byte         `json:"feeRecipient"`        // Platform identity (DER certificate) whose balance receives the fee, fixed when the auction is created
+	MaxBids              uint32         `json:"maxBids"`             // Maximum number of bids (0 means unlimited)
+	AnonymousIDs         bool           `json:"anonymousIDs"`        // If set, events contain anonymous IDs instead of certificates
+	MinBidPrice          uint64         `json:"minBidPrice"`         // Revealed bids below this price are marked ineligible and never counted (0 means no minimum)
+	MaxBidPrice          uint64         `json:"maxBidPrice"`         // Revealed bids above this price are marked ineligible and never counted (0 means no maximum)
+	Winner               []byte         `json:"winner"`
+	HammerPrice          uint64         `json:"hammerPrice"`
+	Result               *AuctionResult `json:"result"`               // It is set when the auction ends
+	FinalizedBy          string         `json:"finalizedBy"`          // MSP ID of the client who ended the auction
+	EndorsingOrgs        []string       `json:"endorsingOrgs"`        // Orgs which could endorse according to the auction's endorsement policy when it ended
+	EndorsementQuorum    int            `json:"endorsementQuorum"`    // Number of the endorsing orgs which had to endorse the end (0 if they all had to, for auctions ended before quorums were supported)
+	Version              uint64         `json:"version"`              // Number of times the auction has been saved, it increases with every change and clients can pass it as expected version
+	LastUpdated          int64          `json:"lastUpdated"`          // Unix time of the transaction which saved the auction record last, bids and reveals do not change it (0 for auctions saved before it was recorded)
+	StartPrice           uint64         `json:"startPrice"`           // Clock price of a Dutch auction when it is created
+	PriceDecrement       uint64         `json:"priceDecrement"`       // Amount by which the clock price of a Dutch auction decreases per interval
+	DecrementInterval    int64          `json:"decrementInterval"`    // Length of a clock interval of a Dutch auction in seconds
+	ClockStart           int64          `json:"clockStart"`           // Unix time when the clock of a Dutch auction started
+	BidIncrement         uint64         `json:"bidIncrement"`         // Minimum amount by which a bid must outbid the standing high bid of an English auction
+	Description          string         `json:"description"`          // Human-readable description of the item
+	MetadataURI          string         `json:"metadataURI"`          // Optional link to further information about the item
+	RequireBidSignature  bool           `json:"requireBidSignature"`  // If set, only bids submitted with BidSigned are accepted
+	SoleBidderPaysOwnBid bool           `json:"soleBidderPaysOwnBid"` // If set, winners without a losing competitor pay their own bid instead of the reserve price
+	Unsealed             bool           `json:"unsealed"`             // If set, bids are placed openly with their price instead of a hidden commit
+	RedactRevealedPrices bool           `json:"redactRevealedPrices"` // If set, reveal events do not contain the revealed prices
+	Currency             string         `json:"currency"`             // Unit of all prices, e.g. "USD" (empty if unspecified)
+	CommitDifficulty     uint8          `json:"commitDifficulty"`     // Number of leading zero bits which hidden commits must have (0 means no requirement)
+	HideBidCount         bool           `json:"hideBidCount"`         // If set, the number of bids is only disclosed after the auction has ended
+	HighBidder           []byte         `json:"highBidder"`           // Bidder of the standing high bid of an English auction
+	LegacyBids           []Bid          `json:"bids,omitempty"`       // Bids stored inside the auction by earlier versions of the chaincode, MigrateBids moves them to their own keys
+}
+
+// Optional auction settings, the client passes them to CreateAuction as a JSON object
+type AuctionOptions struct {
+	ItemRef              string   `json:"itemRef"`
+	HasReserve           bool     `json:"hasReserve"`
+	ReservePrice         uint64   `json:"reservePrice"`
+	AllowedMSPs          []string `json:"allowedMSPs"`
+	PriceScale           uint8    `json:"priceScale"`
+	EarliestClose        int64    `json:"earliestClose"`
+	ScrubLosersAfterEnd  bool     `json:"scrubLosersAfterEnd"`
+	MinSaltBytes         uint32   `json:"minSaltBytes"`
+	Quantity             uint64   `json:"quantity"`
+	AuctionType          string   `json:"auctionType"` // "vickrey" (default), "firstPrice", "allPay", "dutch" or "english"
+	MinDeposit           uint64   `json:"minDeposit"`
+	FeeBps               uint16   `json:"feeBps"`
+	FeePayer             string   `json:"feePayer"` // "seller" (default) or "buyer"
+	MaxBids              uint32   `json:"maxBids"`
+	AnonymousIDs         bool     `json:"anonymousIDs"`
+	MinBidPrice          uint64   `json:"minBidPrice"`
+	MaxBidPrice          uint64   `json:"maxBidPrice"`
+	StartPrice           uint64   `json:"startPrice"`
+	PriceDecrement       uint64   `json:"priceDecrement"`
+	DecrementInterval    int64    `json:"decrementInterval"`
+	BidIncrement         uint64   `json:"bidIncrement"`
+	Description          string   `json:"description"`
+	MetadataURI          string   `json:"metadataURI"`
+	RequireBidSignature  bool     `json:"requireBidSignature"`
+	SoleBidderPaysOwnBid bool     `json:"soleBidderPaysOwnBid"`
+	Unsealed             bool     `json:"unsealed"`
+	RedactRevealedPrices bool     `json:"redactRevealedPrices"`
+	Currency             string   `json:"currency"`
+	CommitDifficulty     uint8    `json:"commitDifficulty"`
+	HideBidCount         bool     `json:"hideBidCount"`
 }
 
 // Auction status information, which will be presented to the users in an event
 type AuctionSummary struct {
+	SchemaVersion  int            `json:"schemaVersion"` // Version of this layout, consumers can branch on it
 	Name           string         `json:"name"`
 	Seller         []byte         `json:"seller"`
 	Status         AuctionStatus  `json:"status"`
+	Type           AuctionType    `json:"type"`
 	DirectBuyPrice uint64         `json:"directBuyPrice"`
-	Result         *AuctionResult `json:"result"` // It is set when the auction ends
+	PriceScale     uint8          `json:"priceScale"`
+	EarliestClose  int64          `json:"earliestClose"`
+	NumBidders     int            `json:"numBidders"`    // Number of distinct buyers who submitted bids, without ineligible ones, 0 while the auction hides its number of bids
+	Result         *AuctionResult `json:"result"`        // It is set when the auction ends
+	AnonymousIDs   bool           `json:"anonymousIDs"`  // If set, the certificates are replaced by anonymous IDs
+	NumVoidedBids  int            `json:"numVoidedBids"` // Number of hidden bids which a direct buy made void
+	Version        uint64         `json:"version"`       // Version of the auction state the summary was built from
+	LastUpdated    int64          `json:"lastUpdated"`   // Unix time of the last change of the auction record, bids and reveals do not change it
+	Currency       string         `json:"currency"`      // Unit of all prices, empty if unspecified
+	Description    string         `json:"description"`
+	MetadataURI    string         `json:"metadataURI"`
+	BidBook        []BidBookEntry `json:"bidBook,omitempty"` // Revealed bids, only set in the event of EndAuction
 }
 
-type AuctionResult struct {
-	Winner      []byte `json:"winner"`
-	DirectBuy   bool   `json:"directBuy"` // If true, the winner bought directly, otherwise they were the highest bidder
+// Revealed bid in the bid book of an ended auction
+// With the bid book, anyone can recompute the winners and the hammer price of the result.
+type BidBookEntry struct {
+	BuyerHash []byte `json:"buyerHash"` // Anonymous ID of the buyer, the same for all bids of the buyer in this auction
+	BidPrice  uint64 `json:"bidPrice"`
+}
+
+// Summaries of all auctions, as returned by GetAllAuctionSummaries
+type AuctionSummaryList struct {
+	Summaries []*AuctionSummary `json:"summaries"`
+	Truncated bool              `json:"truncated"` // If set, more auctions exist than summaries could be returned
+}
+
+// State of an auction after a transaction which modified it
+type AuctionHistoryEntry struct {
+	TxID      string          `json:"txID"`
+	Timestamp int64           `json:"timestamp"` // Unix time of the transaction
+	IsDelete  bool            `json:"isDelete"`  // The auction was deleted, e.g. because it was cancelled
+	Summary   *AuctionSummary `json:"summary"`   // nil for deletions, the number of bidders is not part of the history
+}
+
+// Information about a newly submitted bid, which will be presented to the users in an event
+// It does not contain the hidden commit.
+type BidEvent struct {
+	AuctionName string `json:"auctionName"`
+	BidID       string `json:"bidID"`
+	Buyer       []byte `json:"buyer"`
+	NumBids     int    `json:"numBids,omitempty"` // Number of bids of the auction so far, left out if the auction hides it
+	Price       uint64 `json:"price,omitempty"`   // Price of an open bid, sealed bids have none
+}
+
+// Information about the bids revealed in a transaction, which will be presented to the users in an event
+type RevealEvent struct {
+	AuctionName string   `json:"auctionName"`
+	Buyer       []byte   `json:"buyer"`
+	NumRevealed int      `json:"numRevealed"`
+	Prices      []uint64 `json:"prices,omitempty"` // Revealed prices, unless the auction redacts them
+}
+
+// Information about the bids of a bidder which the seller struck, which will be presented to the users in an event
+type StrikeEvent struct {
+	AuctionName string `json:"auctionName"`
+	Buyer       []byte `json:"buyer"`
+	NumStruck   int    `json:"numStruck"`
+}
+
+// Bid price and hex encoded salt of a bid which is revealed by OpenBids
+type BidReveal struct {
+	BidPrice uint64 `json:"bidPrice"`
+	Salt     string `json:"salt"`
+}
+
+// Winner of an ended auction, as returned by GetWinner
+type WinnerInfo struct {
+	WinnerPem   string `json:"winnerPem"` // Certificate of the winner in PEM format
+	DirectBuy   bool   `json:"directBuy"`
 	HammerPrice uint64 `json:"hammerPrice"`
 }
+
+// State-based endorsement policy of an auction, as returned by GetAuctionEndorsementPolicy
+type EndorsementPolicy struct {
+	Orgs   []string `json:"orgs"`   // MSP IDs of the orgs whose peers can endorse, empty if only the chaincode endorsement policy applies
+	Quorum int      `json:"quorum"` // Number of these orgs which must endorse
+}
+
+// Certificate fingerprints of the seller and the winner of an auction, as returned by GetAuctionFingerprints
+// They are hex encoded SHA-256 hashes of the DER certificates, UIs can show them instead of the full certificates.
+type AuctionFingerprints struct {
+	Seller string `json:"seller"`
+	Winner string `json:"winner"` // Empty if the auction has not ended or has no winner
+}
+
+// Outcome of a single reveal of OpenBids
+type RevealResult struct {
+	BidPrice uint64 `json:"bidPrice"`
+	Revealed bool   `json:"revealed"`
+	Error    string `json:"error"` // Reason why the bid could not be revealed, empty if it was revealed
+}
+
+// Number of revealed bids and of all bids of an auction, as returned by GetRevealProgress
+type RevealProgress struct {
+	Revealed int `json:"revealed"`
+	Total    int `json:"total"`
+}
+
+// Client-friendly view of an auction, as returned by GetAuctionJSON
+// Certificates are PEM encoded and the status and type are strings.
+type AuctionView struct {
+	Name              string        `json:"name"`
+	SellerPem         string        `json:"sellerPem"`
+	Status            AuctionStatus `json:"status"`
+	Type              AuctionType   `json:"type"`
+	DirectBuyPrice    uint64        `json:"directBuyPrice"`
+	PriceScale        uint8         `json:"priceScale"`
+	Currency          string        `json:"currency"`
+	ItemRef           string        `json:"itemRef"`
+	HasReserve        bool          `json:"hasReserve"`
+	ReservePrice      uint64        `json:"reservePrice"`
+	EarliestClose     int64         `json:"earliestClose"`
+	AllowedMSPs       []string      `json:"allowedMSPs"`
+	InvitedBidderPems []string      `json:"invitedBidderPems"`
+	Quantity          uint64        `json:"quantity"` // Number of units, at least 1
+	MinDeposit        uint64        `json:"minDeposit"`
+	MinBidPrice       uint64        `json:"minBidPrice"`
+	MaxBidPrice       uint64        `json:"maxBidPrice"`
+	Unsealed          bool          `json:"unsealed"`
+	Description       string        `json:"description"`
+	MetadataURI       string        `json:"metadataURI"`
+	WinnerPems        []string      `json:"winnerPems"` // Empty until the auction has ended
+	HammerPrice       uint64        `json:"hammerPrice"`
+	DirectBuy         bool          `json:"directBuy"`
+	Version           uint64        `json:"version"`
+	Bids              []BidView     `json:"bids"` // Empty while the auction hides its number of bids
+}
+
+// Bid in an AuctionView, the hidden commit is left out while the auction is open
+type BidView struct {
+	ID                string `json:"id"`
+	BidderFingerprint string `json:"bidderFingerprint"` // Hex encoded SHA-256 fingerprint of the bidder certificate
+	BidPrice          uint64 `json:"bidPrice"`          // 0 means hidden
+	HiddenCommit      string `json:"hiddenCommit,omitempty"`
+	Timestamp         int64  `json:"timestamp"`
+	Ineligible        bool   `json:"ineligible,omitempty"` // Set if the revealed price is outside the auction's price range
+}
+
+// Hidden commit of a bid together with the bidder, without the bid price
+type BidCommitment struct {
+	BidderFingerprint string `json:"bidderFingerprint"` // Hex encoded SHA-256 fingerprint of the bidder certificate, or the anonymous ID if the auction uses them
+	HiddenCommit      string `json:"hiddenCommit"`      // Hex encoded
+}
+
+// Byte layout of the preimage of a hidden commit
+// The fields are concatenated in order without any separators or length prefixes.
+type CommitmentPreimageLayout struct {
+	FormatVersion string          `json:"formatVersion"`
+	HashFunction  string          `json:"hashFunction"`
+	OutputLength  int             `json:"outputLength"` // Length of the hidden commit in bytes
+	Fields        []PreimageField `json:"fields"`
+	LeadingZeros  int             `json:"leadingZeros"` // Number of leading zero bits the hidden commit must have, clients choose salts until it has them
+}
+
+type PreimageField struct {
+	Name      string `json:"name"`
+	Encoding  string `json:"encoding"`
+	Length    int    `json:"length"` // Length in bytes, 0 means variable length
+	MinLength int    `json:"minLength"`
+}
+
+// Number of bids submitted in a time bucket
+type BidBucket struct {
+	Start   int64 `json:"start"` // Unix time at which the bucket starts
+	NumBids int   `json:"numBids"`
+}
+
+type AuctionResult struct {
+	Winner      []byte   `json:"winner"`             // The highest winning bidder, or the direct buyer
+	Winners     [][]byte `json:"winners"`            // All winners of a multi-unit auction, starting with Winner
+	DirectBuy   bool     `json:"directBuy"`          // If true, the winner bought directly, otherwise they were the highest bidder
+	HammerPrice uint64   `json:"hammerPrice"`        // Uniform price which every winner pays, the lowest charge if the winners pay their own bids
+	Charges     []Charge `json:"charges"`            // What every bidder pays in an all-pay auction, or every winner in a multi-unit first-price auction
+	Refunds     []Charge `json:"refunds,omitempty"`  // Deposit amounts which were returned to the balances of the bidders
+	Fee         uint64   `json:"fee,omitempty"`      // Platform fee which was paid to the balance of the fee recipient
+	Currency    string   `json:"currency,omitempty"` // Unit of the amounts, empty if unspecified
+}
+
+// Expected result of a closed auction, as returned by PreviewResult
+// If more bidders bid the lowest winning price than units are left, EndAuction chooses among them pseudo-randomly,
+// therefore the preview lists them as tied bidders instead of winners.
+type ResultPreview struct {
+	Result       *AuctionResult `json:"result"`
+	TiedBidders  [][]byte       `json:"tiedBidders"`  // Certificates of the bidders who compete for the remaining units
+	NumTiedUnits uint64         `json:"numTiedUnits"` // Number of units which go to the tied bidders
+}
+
+// Amount which a bidder has to pay
+type Charge struct {
+	Bidder []byte `json:"bidder"`
+	Amount uint64 `json:"amount"`
+}
@@ -5,12 +5,27 @@ SPDX-License-Identifier: Apache-2.0
 package auction
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -19,32 +34,1055 @@ func auctionKey(auctionName string) string {
 	return fmt.Sprintf("auction %s", auctionName)
 }
 
+// validateAuctionName checks that the auction name can be used in world state keys
+// Fabric composite keys use U+0000 as delimiter and U+10FFFF as the end of a range, and they must be valid UTF-8.
+// Only printable characters are allowed, which excludes both as well as other control characters,
+// and the length is limited, because the name is repeated in the key of every bid and deposit.
+func validateAuctionName(auctionName string, maxLength int) error {
+	if auctionName == "" {
+		return fmt.Errorf("auction name must not be empty")
+	}
+	if len(auctionName) > maxLength {
+		return fmt.Errorf("auction name must be at most %d bytes long", maxLength)
+	}
+	if !utf8.ValidString(auctionName) {
+		return fmt.Errorf("auction name must be valid UTF-8")
+	}
+	for i, r := range auctionName {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("auction name must only contain printable characters, found %U at byte %d", r, i)
+		}
+	}
+	return nil
+}
+
+// bidKey gets the composite world state key of a bid
+// Each bid is stored under its own key, so that submitting a bid does not rewrite the whole auction.
+func bidKey(ctx contractapi.TransactionContextInterface, auctionName string, bidID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("bid", []string{auctionName, bidID})
+}
+
+// checkNoLegacyBids returns an error if the auction still stores bids inline, which were not moved to their own keys yet
+// Reading only the bid keys would silently ignore these bids.
+func checkNoLegacyBids(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	auction, err := getAuction(ctx, auctionName)
+	if err != nil {
+		return err
+	}
+	if auction != nil && len(auction.LegacyBids) != 0 {
+		return newAuctionError(ErrCodeInvalidStatus, "the auction still stores %d bids inline, move them to their own keys with MigrateBids first", len(auction.LegacyBids))
+	}
+	return nil
+}
+
+// getListingBids returns the bids from which the summary of an auction in a listing is built
+// Auctions which still store their bids inline are summarized from them, so that a single auction
+// which nobody migrates, e.g. one which ended before the upgrade, does not make the listing fail for everybody.
+func getListingBids(ctx contractapi.TransactionContextInterface, auction *Auction) ([]Bid, error) {
+	if len(auction.LegacyBids) != 0 {
+		return auction.LegacyBids, nil
+	}
+	return getBids(ctx, auction.Name)
+}
+
+// getBids returns all bids of an auction
+// It fails if the auction still has bids stored inline by an earlier version of the chaincode.
+func getBids(ctx contractapi.TransactionContextInterface, auctionName string) ([]Bid, error) {
+	err := checkNoLegacyBids(ctx, auctionName)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("bid", []string{auctionName})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	bids := []Bid{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var bid Bid
+		err = json.Unmarshal(entry.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, nil
+}
+
+// countBids returns the number of bids of an auction without decoding them
+// It reads all bid keys of the auction, so a concurrent new bid makes the transaction fail validation with a phantom read conflict.
+func countBids(ctx contractapi.TransactionContextInterface, auctionName string) (int, error) {
+	err := checkNoLegacyBids(ctx, auctionName)
+	if err != nil {
+		return 0, err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("bid", []string{auctionName})
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+
+	numBids := 0
+	for iterator.HasNext() {
+		_, err := iterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		numBids += 1
+	}
+	return numBids, nil
+}
+
+// putBid saves a bid of an auction to the world state
+// The bid gets the endorsement policy of the auction, so that the policy covers the bids, too.
+func putBid(ctx contractapi.TransactionContextInterface, auctionName string, bid *Bid) error {
+	key, err := bidKey(ctx, auctionName, bid.ID)
+	if err != nil {
+		return err
+	}
+	bidJSON, err := json.Marshal(bid)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, bidJSON)
+	if err != nil {
+		return err
+	}
+	policy, err := ctx.GetStub().GetStateValidationParameter(auctionKey(auctionName))
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return nil
+	}
+	return ctx.GetStub().SetStateValidationParameter(key, policy)
+}
+
+// deleteBid removes a bid of an auction from the world state
+func deleteBid(ctx contractapi.TransactionContextInterface, auctionName string, bidID string) error {
+	key, err := bidKey(ctx, auctionName, bidID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// bidCommitIndexKey gets the composite key under which the ID of the bid with the given hidden commit is stored
+func bidCommitIndexKey(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommit []byte) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("bidCommit", []string{auctionName, hex.EncodeToString(hiddenCommit)})
+}
+
+// putBidCommitIndex records the bid as the one with its hidden commit, so that a resubmission can be detected
+func putBidCommitIndex(ctx contractapi.TransactionContextInterface, auctionName string, bid *Bid) error {
+	key, err := bidCommitIndexKey(ctx, auctionName, bid.HiddenCommit)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(bid.ID))
+}
+
+// findBidByCommit returns the stored bid with the given hidden commit, or nil if there is none
+// It only reads single keys, so unlike a scan of all bids it does not make concurrent bids conflict.
+// Index entries of withdrawn or replaced bids are not removed, so the bid is checked to still have the commit.
+func findBidByCommit(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommit []byte) (*Bid, error) {
+	indexKey, err := bidCommitIndexKey(ctx, auctionName, hiddenCommit)
+	if err != nil {
+		return nil, err
+	}
+	bidID, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return nil, err
+	}
+	if bidID == nil {
+		return nil, nil
+	}
+	key, err := bidKey(ctx, auctionName, string(bidID))
+	if err != nil {
+		return nil, err
+	}
+	bidJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if bidJSON == nil {
+		return nil, nil
+	}
+	var bid Bid
+	err = json.Unmarshal(bidJSON, &bid)
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(bid.HiddenCommit, hiddenCommit) {
+		return nil, nil
+	}
+	return &bid, nil
+}
+
+// deleteBidCommitIndex removes all hidden commit index entries of an auction
+func deleteBidCommitIndex(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("bidCommit", []string{auctionName})
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(entry.Key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adminKey is the world state key of the contract admin's certificate
+const adminKey = "admin"
+
+// getAdmin returns the DER certificate of the contract admin, or nil if no admin has been set
+func getAdmin(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	return ctx.GetStub().GetState(adminKey)
+}
+
+// checkAdmin returns an error if the submitting client is not the contract admin
+func checkAdmin(ctx contractapi.TransactionContextInterface) error {
+	admin, err := getAdmin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get the admin: %v", err)
+	}
+	if admin == nil {
+		return newAuctionError(ErrCodePermissionDenied, "no admin has been set, call InitLedger first")
+	}
+	clientID, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if !reflect.DeepEqual(clientID.Raw, admin) {
+		return newAuctionError(ErrCodePermissionDenied, "only the admin can perform this operation")
+	}
+	return nil
+}
+
+// feeRecipientKey is the world state key of the platform identity which receives the fees of new auctions
+const feeRecipientKey = "feeRecipient"
+
+// getFeeRecipient returns the DER certificate of the configured fee recipient, or nil if the admin has not configured one
+func getFeeRecipient(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	return ctx.GetStub().GetState(feeRecipientKey)
+}
+
+// feeAmount computes the fee in basis points of an amount, rounded down
+// The amount is split, so that the multiplication cannot overflow.
+func feeAmount(amount uint64, feeBps uint16) uint64 {
+	return amount/maxFeeBps*uint64(feeBps) + amount%maxFeeBps*uint64(feeBps)/maxFeeBps
+}
+
+// maxAuctionNameLengthKey is the world state key of the name length limit configured by the admin
+const maxAuctionNameLengthKey = "maxAuctionNameLength"
+
+// getMaxAuctionNameLength returns the maximum length of new auction names in bytes
+// Without a configured limit, defaultMaxAuctionNameLength applies.
+func getMaxAuctionNameLength(ctx contractapi.TransactionContextInterface) (int, error) {
+	maxLengthJSON, err := ctx.GetStub().GetState(maxAuctionNameLengthKey)
+	if err != nil {
+		return 0, err
+	}
+	if maxLengthJSON == nil {
+		return defaultMaxAuctionNameLength, nil
+	}
+	var maxLength uint32
+	err = json.Unmarshal(maxLengthJSON, &maxLength)
+	if err != nil {
+		return 0, err
+	}
+	return int(maxLength), nil
+}
+
+// balanceKey gets the world state key of a client's balance
+// Clients are identified by the fingerprint of their certificate.
+func balanceKey(clientFingerprint string) string {
+	return fmt.Sprintf("balance %s", clientFingerprint)
+}
+
+// getBalance returns the balance of a client, clients without a stored balance have a balance of 0
+func getBalance(ctx contractapi.TransactionContextInterface, clientFingerprint string) (uint64, error) {
+	balanceJSON, err := ctx.GetStub().GetState(balanceKey(clientFingerprint))
+	if err != nil {
+		return 0, err
+	}
+	if balanceJSON == nil {
+		return 0, nil
+	}
+	var balance uint64
+	err = json.Unmarshal(balanceJSON, &balance)
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// putBalance saves the balance of a client to the world state
+func putBalance(ctx contractapi.TransactionContextInterface, clientFingerprint string, balance uint64) error {
+	balanceJSON, err := json.Marshal(balance)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(balanceKey(clientFingerprint), balanceJSON)
+}
+
+// addBalance adds an amount to the balance of a client
+func addBalance(ctx contractapi.TransactionContextInterface, clientFingerprint string, amount uint64) error {
+	balance, err := getBalance(ctx, clientFingerprint)
+	if err != nil {
+		return err
+	}
+	if balance > math.MaxUint64-amount {
+		return fmt.Errorf("balance would overflow")
+	}
+	return putBalance(ctx, clientFingerprint, balance+amount)
+}
+
+// depositKey gets the composite key of the deposit which a bidder locked for an auction
+func depositKey(ctx contractapi.TransactionContextInterface, auctionName string, bidderFingerprint string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("deposit", []string{auctionName, bidderFingerprint})
+}
+
+// lockDeposit moves the minimum deposit of the auction from the bidder's balance to the auction
+// Each bidder locks the deposit only once per auction, no matter how many bids they submit.
+func lockDeposit(ctx contractapi.TransactionContextInterface, auction *Auction, bidderFingerprint string) error {
+	if auction.MinDeposit == 0 {
+		return nil
+	}
+	key, err := depositKey(ctx, auction.Name, bidderFingerprint)
+	if err != nil {
+		return err
+	}
+	lockedDeposit, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if lockedDeposit != nil {
+		return nil
+	}
+	balance, err := getBalance(ctx, bidderFingerprint)
+	if err != nil {
+		return err
+	}
+	if balance < auction.MinDeposit {
+		return newAuctionError(ErrCodeInsufficientBalance, "insufficient balance for the deposit of %d, the balance is %d", auction.MinDeposit, balance)
+	}
+	err = putBalance(ctx, bidderFingerprint, balance-auction.MinDeposit)
+	if err != nil {
+		return err
+	}
+	depositJSON, err := json.Marshal(auction.MinDeposit)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, depositJSON)
+}
+
+// refundDeposit returns the deposit which a bidder locked for an auction, if there is one
+func refundDeposit(ctx contractapi.TransactionContextInterface, auctionName string, bidderFingerprint string) error {
+	key, err := depositKey(ctx, auctionName, bidderFingerprint)
+	if err != nil {
+		return err
+	}
+	depositJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if depositJSON == nil {
+		return nil
+	}
+	var deposit uint64
+	err = json.Unmarshal(depositJSON, &deposit)
+	if err != nil {
+		return err
+	}
+	err = addBalance(ctx, bidderFingerprint, deposit)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// settleDeposits settles the payments of an ended auction and releases all deposits
+// Every bidder pays what they owe, the charge if the result lists charges and otherwise the hammer price for the winners,
+// first from their deposit and then from their balance. The payments go to the seller's balance and the rest of the deposits is refunded.
+// The platform fee goes to the fee recipient's balance, it is deducted from the seller's proceeds or owed by the buyers on top,
+// depending on the fee payer. A buyer pays the price before the fee. Amounts which exceed deposit and balance have to be settled outside of the ledger.
+// Auctions without a minimum deposit are settled outside of the ledger completely. It returns the refunds and the fee.
+func settleDeposits(ctx contractapi.TransactionContextInterface, auction *Auction) ([]Charge, uint64, error) {
+	if auction.MinDeposit == 0 {
+		return nil, 0, nil
+	}
+
+	// Deposits are stored by the fingerprint of the bidder, the refunds list their certificates
+	result := auctionResult(auction)
+	owed := make(map[string]uint64)
+	bidders := make(map[string][]byte)
+	if result.Charges != nil {
+		for _, charge := range result.Charges {
+			owed[certFingerprint(charge.Bidder)] = charge.Amount
+		}
+	} else {
+		for _, winner := range result.Winners {
+			owed[certFingerprint(winner)] = auction.HammerPrice
+		}
+	}
+	for _, winner := range result.Winners {
+		bidders[certFingerprint(winner)] = winner
+	}
+	bids, err := getBids(ctx, auction.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range bids {
+		bidders[certFingerprint(bids[i].Buyer)] = bids[i].Buyer
+	}
+
+	// Buyers who pay the fee owe it on top, it is the part of their debt which is paid last
+	owedFees := make(map[string]uint64)
+	if auction.FeePayer == feePayerBuyer {
+		for bidderFingerprint, amount := range owed {
+			fee := feeAmount(amount, auction.FeeBps)
+			if amount > math.MaxUint64-fee {
+				return nil, 0, fmt.Errorf("amount owed with the fee would overflow")
+			}
+			owed[bidderFingerprint] = amount + fee
+			owedFees[bidderFingerprint] = fee
+		}
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("deposit", []string{auction.Name})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iterator.Close()
+
+	sellerPayment := uint64(0)
+	platformFee := uint64(0)
+	pay := func(bidderFingerprint string, payment uint64) error {
+		fee := uint64(0)
+		if price := owed[bidderFingerprint] - owedFees[bidderFingerprint]; payment > price {
+			fee = payment - price
+		}
+		owedFees[bidderFingerprint] -= fee
+		if sellerPayment > math.MaxUint64-(payment-fee) || platformFee > math.MaxUint64-fee {
+			return fmt.Errorf("payment to the seller would overflow")
+		}
+		sellerPayment += payment - fee
+		platformFee += fee
+		return nil
+	}
+	refunds := []Charge{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, 0, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(keyParts) != 2 {
+			return nil, 0, fmt.Errorf("invalid deposit key")
+		}
+		bidderFingerprint := keyParts[1]
+		var deposit uint64
+		err = json.Unmarshal(entry.Value, &deposit)
+		if err != nil {
+			return nil, 0, err
+		}
+		payment := deposit
+		if owed[bidderFingerprint] < payment {
+			payment = owed[bidderFingerprint]
+		}
+		err = pay(bidderFingerprint, payment)
+		if err != nil {
+			return nil, 0, err
+		}
+		owed[bidderFingerprint] -= payment
+		deposit -= payment
+		if deposit != 0 {
+			err = addBalance(ctx, bidderFingerprint, deposit)
+			if err != nil {
+				return nil, 0, err
+			}
+			refunds = append(refunds, Charge{
+				Bidder: bidders[bidderFingerprint],
+				Amount: deposit,
+			})
+		}
+		err = ctx.GetStub().DelState(entry.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// The rest is paid from the balances, in a fixed order so that all endorsers write the same state
+	// A bidder who got a refund owes nothing anymore, so no balance is read after it was written.
+	owingFingerprints := make([]string, 0, len(owed))
+	for bidderFingerprint, amount := range owed {
+		if amount != 0 {
+			owingFingerprints = append(owingFingerprints, bidderFingerprint)
+		}
+	}
+	sort.Strings(owingFingerprints)
+	for _, bidderFingerprint := range owingFingerprints {
+		balance, err := getBalance(ctx, bidderFingerprint)
+		if err != nil {
+			return nil, 0, err
+		}
+		payment := balance
+		if owed[bidderFingerprint] < payment {
+			payment = owed[bidderFingerprint]
+		}
+		if payment == 0 {
+			continue
+		}
+		err = pay(bidderFingerprint, payment)
+		if err != nil {
+			return nil, 0, err
+		}
+		err = putBalance(ctx, bidderFingerprint, balance-payment)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// A seller who pays the fee gets their proceeds without it
+	if auction.FeePayer != feePayerBuyer {
+		fee := feeAmount(sellerPayment, auction.FeeBps)
+		sellerPayment -= fee
+		platformFee += fee
+	}
+
+	// The credits are added up first, as the balance of a seller who is also the fee recipient must only be written once
+	credits := make(map[string]uint64)
+	credits[certFingerprint(auction.Seller)] = sellerPayment
+	if platformFee != 0 {
+		recipientFingerprint := certFingerprint(auction.FeeRecipient)
+		if credits[recipientFingerprint] > math.MaxUint64-platformFee {
+			return nil, 0, fmt.Errorf("payment to the fee recipient would overflow")
+		}
+		credits[recipientFingerprint] += platformFee
+	}
+	creditedFingerprints := make([]string, 0, len(credits))
+	for fingerprint, amount := range credits {
+		if amount != 0 {
+			creditedFingerprints = append(creditedFingerprints, fingerprint)
+		}
+	}
+	sort.Strings(creditedFingerprints)
+	for _, fingerprint := range creditedFingerprints {
+		err = addBalance(ctx, fingerprint, credits[fingerprint])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return refunds, platformFee, nil
+}
+
+// decodeHiddenCommit decodes a hex encoded hidden commit and checks its length
+func decodeHiddenCommit(hiddenCommitHex string) ([]byte, error) {
+	hiddenCommit, err := hex.DecodeString(hiddenCommitHex)
+	if err != nil {
+		return nil, newAuctionError(ErrCodeInvalidArgument, "could not decode hidden commit: %v", err)
+	}
+
+	// The hiddenCommit should be a 512 bit long hash
+	if len(hiddenCommit) != bidCommitmentLength {
+		return nil, newAuctionError(ErrCodeInvalidArgument, "hiddenCommit is not 512 bit long")
+	}
+	return hiddenCommit, nil
+}
+
+// isBidCountVisible tells whether the number of bids of the auction may be disclosed
+// Auctions with HideBidCount disclose it only after they have ended.
+func isBidCountVisible(auction *Auction) bool {
+	return !auction.HideBidCount || auction.Status == AuctionStatus(Ended)
+}
+
+// checkBidCountVisible returns an error if the auction hides its number of bids
+// Queries which list the bids or their bidders must call it, too, as the count can be derived from them.
+func checkBidCountVisible(auction *Auction) error {
+	if !isBidCountVisible(auction) {
+		return newAuctionError(ErrCodePermissionDenied, "the number of bids of this auction is hidden until it has ended")
+	}
+	return nil
+}
+
+// checkCommitDifficulty returns an error if the hidden commit has fewer leading zero bits than the auction requires
+// Finding a salt for a given price then takes about 2^difficulty hash computations, which slows down
+// bidders who grind through salts, e.g. to submit many commits for different prices.
+func checkCommitDifficulty(auction *Auction, hiddenCommit []byte) error {
+	zeroBits := leadingZeroBits(hiddenCommit)
+	if zeroBits < int(auction.CommitDifficulty) {
+		return newAuctionError(ErrCodeInvalidArgument, "the hidden commit must start with %d zero bits, it starts with %d", auction.CommitDifficulty, zeroBits)
+	}
+	return nil
+}
+
+// leadingZeroBits counts the zero bits at the start of the data
+func leadingZeroBits(data []byte) int {
+	count := 0
+	for _, b := range data {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
+
+// checkBidderAllowed returns an error if the client cannot bid on the auction
+func checkBidderAllowed(ctx contractapi.TransactionContextInterface, auction *Auction, clientCert []byte) error {
+	// Can only submit new bid while auction is open
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction is closed")
+	}
+
+	// The seller must not drive up the price of their own auction
+	if reflect.DeepEqual(clientCert, auction.Seller) {
+		return newAuctionError(ErrCodePermissionDenied, "seller cannot bid on their own auction")
+	}
+
+	// The fee recipient is credited in the same settlement, so it must not be a bidder, too
+	if auction.FeeRecipient != nil && reflect.DeepEqual(clientCert, auction.FeeRecipient) {
+		return newAuctionError(ErrCodePermissionDenied, "the fee recipient cannot bid on the auction")
+	}
+
+	// Check if the client's organization may participate
+	errMSP := checkClientMSPAllowed(ctx, auction.AllowedMSPs)
+	if errMSP != nil {
+		return errMSP
+	}
+
+	// Check if the client is invited, if the auction is private
+	if !isBidderInvited(auction, clientCert) {
+		return newAuctionError(ErrCodePermissionDenied, "the client is not invited to this auction")
+	}
+	return nil
+}
+
+// checkSealedBids returns an error if the auction does not take sealed bids
+func checkSealedBids(auction *Auction) error {
+	if auction.Type == AuctionType(Dutch) {
+		return newAuctionError(ErrCodeInvalidStatus, "Dutch auctions do not take bids, the clock price has to be accepted")
+	}
+	if auction.Type == AuctionType(English) {
+		return newAuctionError(ErrCodeInvalidStatus, "English auctions take open bids, use PlaceBid")
+	}
+	if auction.Unsealed {
+		return newAuctionError(ErrCodeInvalidStatus, "the auction is unsealed and takes open bids, use PlaceBid")
+	}
+	return nil
+}
+
+// takesOpenBids tells whether bids are placed with their price instead of a hidden commit
+func takesOpenBids(auction *Auction) bool {
+	if auction.Type == AuctionType(Dutch) {
+		return false
+	}
+	return auction.Type == AuctionType(English) || auction.Unsealed
+}
+
+// englishMinimumBid returns the lowest price which a new bid of an English auction must offer
+// The hammer price of an open English auction is the standing high bid.
+func englishMinimumBid(auction *Auction) (uint64, error) {
+	if auction.HammerPrice == 0 {
+		if auction.MinBidPrice == 0 {
+			return 1, nil
+		}
+		return auction.MinBidPrice, nil
+	}
+	increment := auction.BidIncrement
+	if increment == 0 {
+		increment = 1
+	}
+	if auction.HammerPrice > math.MaxUint64-increment {
+		return 0, newAuctionError(ErrCodeInvalidStatus, "the high bid cannot be outbid anymore")
+	}
+	return auction.HammerPrice + increment, nil
+}
+
+// dutchClockPrice computes the clock price of a Dutch auction at the given Unix time
+// The price decreases by the decrement after every full interval, but never falls below the reserve price.
+func dutchClockPrice(auction *Auction, timestamp int64) uint64 {
+	floor := uint64(0)
+	if auction.HasReserve {
+		floor = auction.ReservePrice
+	}
+	if timestamp <= auction.ClockStart || auction.DecrementInterval <= 0 {
+		return auction.StartPrice
+	}
+	steps := uint64((timestamp - auction.ClockStart) / auction.DecrementInterval)
+	if steps != 0 && auction.PriceDecrement > (auction.StartPrice-floor)/steps {
+		return floor
+	}
+	return auction.StartPrice - steps*auction.PriceDecrement
+}
+
+// getRevealableAuction gets an auction whose bids the given client can reveal, together with its bids
+func getRevealableAuction(ctx contractapi.TransactionContextInterface, auctionName string, clientCert []byte) (*Auction, []Bid, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Open bids have no hidden price
+	if takesOpenBids(auction) {
+		return nil, nil, newAuctionError(ErrCodeInvalidStatus, "the auction takes open bids, which need not be revealed")
+	}
+
+	// Bids can only be revealed after bidding has closed and before the auction has ended
+	if auction.Status != AuctionStatus(Closed) {
+		return nil, nil, newAuctionError(ErrCodeInvalidStatus, "bids can only be revealed while the auction is closed")
+	}
+
+	// The seller must not drive up the price of their own auction
+	if reflect.DeepEqual(clientCert, auction.Seller) {
+		return nil, nil, newAuctionError(ErrCodePermissionDenied, "seller cannot bid on their own auction")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return nil, nil, wrapError(errGetBids, "could not get the bids")
+	}
+
+	return auction, bids, nil
+}
+
+// checkBidPrice returns an error if a revealed or open bid price is out of the auction's range
+// Open bids which fail the check are rejected. Sealed bids which fail it are still revealed, so that the auction
+// can end, but they are marked ineligible.
+func checkBidPrice(auction *Auction, bidPrice uint64) error {
+	if bidPrice == 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "bid price cannot be zero")
+	}
+	if bidPrice < auction.MinBidPrice {
+		return newAuctionError(ErrCodeInvalidArgument, "bid price is below the minimum bid price of %d", auction.MinBidPrice)
+	}
+	if auction.MaxBidPrice != 0 && bidPrice > auction.MaxBidPrice {
+		return newAuctionError(ErrCodeInvalidArgument, "bid price is above the maximum bid price of %d", auction.MaxBidPrice)
+	}
+	return nil
+}
+
+// revealBid reveals the hidden bids of the client which match the given bid price and salt
+// The revealed bids are updated in the given slice, too, because a transaction cannot read its own writes.
+func revealBid(ctx contractapi.TransactionContextInterface, auction *Auction, bids []Bid, clientCert *x509.Certificate, bidPrice uint64, saltHex string) error {
+
+	// A price of 0 marks a hidden bid, so it can never be revealed
+	if bidPrice == 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "bid price cannot be zero")
+	}
+
+	// A committed price outside the range cannot be taken back, so the bid is revealed but cannot win
+	ineligible := checkBidPrice(auction, bidPrice) != nil
+
+	// Decode salt
+	salt, errSaltDecode := hex.DecodeString(saltHex)
+	if errSaltDecode != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "could not decode salt: %v", errSaltDecode)
+	}
+
+	// Check salt minimum requirements
+	if len(salt) < auctionMinSaltLength(auction) {
+		return newAuctionError(ErrCodeInvalidArgument, "salt should be at least %d bytes long", auctionMinSaltLength(auction))
+	}
+
+	bidHash, errHashBid := hashBid(clientCert, bidPrice, salt)
+	if errHashBid != nil {
+		return errHashBid
+	}
+
+	// Revealed bids are immutable, they can never be revealed again
+	alreadyRevealed := false
+	for i := range bids {
+		bid := &bids[i]
+		if reflect.DeepEqual(bid.Buyer, clientCert.Raw) && bid.BidPrice != 0 {
+			alreadyRevealed = true
+			if reflect.DeepEqual(bid.HiddenCommit, bidHash) {
+				return newAuctionError(ErrCodeAlreadyRevealed, "bid has already been revealed")
+			}
+		}
+	}
+
+	// Iterate over the bids and try to reveal any
+	numHiddenBids := 0
+	numRevealedBids := 0
+	for i := range bids {
+		bid := &bids[i]
+		if reflect.DeepEqual(bid.Buyer, clientCert.Raw) && bid.BidPrice == 0 {
+			numHiddenBids += 1
+			// Check if hidden commit matches the hash
+			if reflect.DeepEqual(bid.HiddenCommit, bidHash) {
+				// The bid price is revealed
+				bid.BidPrice = bidPrice
+				bid.Ineligible = ineligible
+				numRevealedBids += 1
+				errPutBid := putBid(ctx, auction.Name, bid)
+				if errPutBid != nil {
+					return fmt.Errorf("could not save the revealed bid: %v", errPutBid)
+				}
+			}
+		}
+	}
+	if numHiddenBids == 0 {
+		if alreadyRevealed {
+			return newAuctionError(ErrCodeAlreadyRevealed, "bid has already been revealed")
+		}
+		return newAuctionError(ErrCodeBidMismatch, "there are no hidden bids from this buyer in the auction")
+	}
+	if numRevealedBids == 0 {
+		return newAuctionError(ErrCodeBidMismatch, "no matching hidden bid found for the revealed price and salt")
+	}
+
+	return nil
+}
+
+// itemRefIndexKey gets the composite key of the item reference index entry for an auction
+func itemRefIndexKey(ctx contractapi.TransactionContextInterface, itemRef string, auctionName string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("itemRef~auction", []string{itemRef, auctionName})
+}
+
+// getAuctionsBySeller returns all auctions of the given seller
+func getAuctionsBySeller(ctx contractapi.TransactionContextInterface, seller []byte) ([]*Auction, error) {
+	return getAuctionsByCertField(ctx, "seller", seller, func(auction *Auction) []byte {
+		return auction.Seller
+	})
+}
+
+// getAuctionsByHighBidder returns all auctions whose standing high bid is from the given bidder
+func getAuctionsByHighBidder(ctx contractapi.TransactionContextInterface, bidder []byte) ([]*Auction, error) {
+	return getAuctionsByCertField(ctx, "highBidder", bidder, func(auction *Auction) []byte {
+		return auction.HighBidder
+	})
+}
+
+// getAuctionsByCertField returns all auctions whose certificate field with the given JSON name equals the certificate
+// It uses a rich query on CouchDB and falls back to scanning all auctions on LevelDB, which does not support rich queries.
+func getAuctionsByCertField(ctx contractapi.TransactionContextInterface, field string, cert []byte, certOf func(*Auction) []byte) ([]*Auction, error) {
+	query, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			field: cert,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	iterator, errQuery := ctx.GetStub().GetQueryResult(string(query))
+	if errQuery != nil {
+		allAuctions, err := getAllAuctions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		auctions := []*Auction{}
+		for _, auction := range allAuctions {
+			if reflect.DeepEqual(certOf(auction), cert) {
+				auctions = append(auctions, auction)
+			}
+		}
+		return auctions, nil
+	}
+	defer iterator.Close()
+
+	auctions := []*Auction{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		// Only auctions have these fields, but other keys are skipped anyway
+		if !strings.HasPrefix(entry.Key, auctionKey("")) {
+			continue
+		}
+		var auction Auction
+		err = json.Unmarshal(entry.Value, &auction)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, &auction)
+	}
+	return auctions, nil
+}
+
+// getAuctionsByItemRef returns the names of the unfinished auctions selling the given item
+func getAuctionsByItemRef(ctx contractapi.TransactionContextInterface, itemRef string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("itemRef~auction", []string{itemRef})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	auctionNames := []string{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("invalid item reference index key")
+		}
+		auctionNames = append(auctionNames, keyParts[1])
+	}
+	return auctionNames, nil
+}
+
+// addItemRefIndex adds the auction to the item reference index, if it has an item reference
+func addItemRefIndex(ctx contractapi.TransactionContextInterface, auction *Auction) error {
+	if auction.ItemRef == "" {
+		return nil
+	}
+	indexKey, err := itemRefIndexKey(ctx, auction.ItemRef, auction.Name)
+	if err != nil {
+		return err
+	}
+	// The value is irrelevant, but a nil value would delete the key
+	return ctx.GetStub().PutState(indexKey, []byte{0})
+}
+
+// removeItemRefIndex removes a finished auction from the item reference index
+func removeItemRefIndex(ctx contractapi.TransactionContextInterface, auction *Auction) error {
+	if auction.ItemRef == "" {
+		return nil
+	}
+	indexKey, err := itemRefIndexKey(ctx, auction.ItemRef, auction.Name)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(indexKey)
+}
+
 // doesAuctionExist checks if an auction with the given name exists in the world state
 func doesAuctionExist(ctx contractapi.TransactionContextInterface, auctionName string) (bool, error) {
 	auctionBin, err := ctx.GetStub().GetState(auctionKey(auctionName))
 	if err != nil {
 		return false, err
 	}
-	exists := auctionBin != nil
-	return exists, nil
+	exists := auctionBin != nil
+	return exists, nil
+}
+
+// AuctionTransactionContext is the transaction context of the auction contract
+// It caches the auctions read in the transaction by their world state key. The contract API creates
+// a new context for every transaction, so the cache never outlives the transaction.
+type AuctionTransactionContext struct {
+	contractapi.TransactionContext
+	auctions map[string]*Auction
+}
+
+// cachedAuction returns the auction stored under the given key if it was already read in this transaction
+// The auction is nil if the transaction deleted it.
+func (ctx *AuctionTransactionContext) cachedAuction(key string) (*Auction, bool) {
+	auction, cached := ctx.auctions[key]
+	return auction, cached
+}
+
+// cacheAuction remembers the auction read under the given key for the rest of the transaction
+// A nil auction records that the transaction deleted it.
+func (ctx *AuctionTransactionContext) cacheAuction(key string, auction *Auction) {
+	if ctx.auctions == nil {
+		ctx.auctions = make(map[string]*Auction)
+	}
+	ctx.auctions[key] = auction
 }
 
 // getAuction retrieves the auction with the given name from the world state
+// It returns nil without an error if the auction does not exist.
+// With an AuctionTransactionContext, repeated reads in the same transaction return the same in-memory auction,
+// including the changes the transaction made to it, without reading the world state again.
+// An auction which the transaction deleted is not found anymore.
 func getAuction(ctx contractapi.TransactionContextInterface, auctionName string) (*Auction, error) {
-	auctionBin, errGetState := ctx.GetStub().GetState(auctionKey(auctionName))
+	key := auctionKey(auctionName)
+	cache, hasCache := ctx.(*AuctionTransactionContext)
+	if hasCache {
+		if auction, cached := cache.cachedAuction(key); cached {
+			return auction, nil
+		}
+	}
+	auctionBin, errGetState := ctx.GetStub().GetState(key)
 	if errGetState != nil {
 		return nil, errGetState
 	}
+	if auctionBin == nil {
+		return nil, nil
+	}
 	var auction Auction
 	err := json.Unmarshal(auctionBin, &auction)
 	if err != nil {
 		return nil, err
 	}
+	if hasCache {
+		cache.cacheAuction(key, &auction)
+	}
 	return &auction, nil
 }
 
+// getAllAuctions returns all auctions in the world state, ordered by name
+// The auction keys are the only keys starting with "auction ", and "!" is the next character after the space.
+func getAllAuctions(ctx contractapi.TransactionContextInterface) ([]*Auction, error) {
+	auctions, _, err := getAuctions(ctx, 0)
+	return auctions, err
+}
+
+// getAuctions returns at most limit auctions in the order of their names (0 means no limit)
+// It also returns whether more auctions exist.
+func getAuctions(ctx contractapi.TransactionContextInterface, limit int) ([]*Auction, bool, error) {
+	iterator, err := ctx.GetStub().GetStateByRange(auctionKey(""), "auction!")
+	if err != nil {
+		return nil, false, err
+	}
+	defer iterator.Close()
+
+	auctions := []*Auction{}
+	for iterator.HasNext() {
+		if limit != 0 && len(auctions) == limit {
+			return auctions, true, nil
+		}
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		var auction Auction
+		err = json.Unmarshal(entry.Value, &auction)
+		if err != nil {
+			return nil, false, err
+		}
+		auctions = append(auctions, &auction)
+	}
+	return auctions, false, nil
+}
+
 // putAuction saves the given auction in the contract world state
+// It increases the version and records the transaction time as the time of the last update.
+// Bids and reveals only write their own bid keys, so they do not update it, otherwise concurrent bids would conflict.
 func putAuction(ctx contractapi.TransactionContextInterface, auction *Auction) error {
+	timestamp, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	auction.Version += 1
+	auction.LastUpdated = timestamp
 	auctionBin, err := json.Marshal(auction)
 	if err != nil {
 		return err
@@ -52,6 +1090,348 @@ func putAuction(ctx contractapi.TransactionContextInterface, auction *Auction) e
 	return ctx.GetStub().PutState(auctionKey(auction.Name), auctionBin)
 }
 
+// checkExpectedVersion returns an error if the client expected another version of the auction than the current one
+// An expected version of 0 skips the check. The check only detects that the client acted on an outdated state,
+// two transactions endorsed on the same version still conflict only when they are committed.
+func checkExpectedVersion(auction *Auction, expectedVersion uint64) error {
+	if expectedVersion != 0 && auction.Version != expectedVersion {
+		return newAuctionError(ErrCodeVersionConflict, "the auction has version %d, but version %d was expected", auction.Version, expectedVersion)
+	}
+	return nil
+}
+
+// deleteAuction removes the auction with the given name from the world state
+// With an AuctionTransactionContext, later reads in the same transaction do not find the auction anymore.
+func deleteAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	key := auctionKey(auctionName)
+	if cache, hasCache := ctx.(*AuctionTransactionContext); hasCache {
+		cache.cacheAuction(key, nil)
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// getAuctionEndorsementPolicy returns the orgs which can endorse changes of the auction and how many of them must endorse,
+// according to its state-based endorsement policy
+// If no such policy is set, the chaincode endorsement policy applies and a policy without orgs is returned.
+func getAuctionEndorsementPolicy(ctx contractapi.TransactionContextInterface, auctionName string) (*EndorsementPolicy, error) {
+	policy, err := ctx.GetStub().GetStateValidationParameter(auctionKey(auctionName))
+	if err != nil {
+		return nil, err
+	}
+	if len(policy) == 0 {
+		return &EndorsementPolicy{Orgs: []string{}, Quorum: 0}, nil
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, err
+	}
+	orgs := endorsementPolicy.ListOrgs()
+	sort.Strings(orgs)
+	envelope := &common.SignaturePolicyEnvelope{}
+	err = proto.Unmarshal(policy, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return &EndorsementPolicy{Orgs: orgs, Quorum: int(envelope.GetRule().GetNOutOf().GetN())}, nil
+}
+
+// newQuorumPolicy builds a state-based endorsement policy which requires the endorsement of peers of quorum of the given orgs
+// statebased.KeyEndorsementPolicy always requires all orgs, so the policy is built like it does, but with the quorum as N.
+func newQuorumPolicy(orgs []string, quorum int) ([]byte, error) {
+	principals := make([]*msp.MSPPrincipal, 0, len(orgs))
+	rules := make([]*common.SignaturePolicy, 0, len(orgs))
+	for i, org := range orgs {
+		principal, err := proto.Marshal(&msp.MSPRole{
+			Role:          msp.MSPRole_PEER,
+			MspIdentifier: org,
+		})
+		if err != nil {
+			return nil, err
+		}
+		principals = append(principals, &msp.MSPPrincipal{
+			PrincipalClassification: msp.MSPPrincipal_ROLE,
+			Principal:               principal,
+		})
+		rules = append(rules, &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_SignedBy{
+				SignedBy: int32(i),
+			},
+		})
+	}
+	return proto.Marshal(&common.SignaturePolicyEnvelope{
+		Version: 0,
+		Rule: &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_NOutOf_{
+				NOutOf: &common.SignaturePolicy_NOutOf{
+					N:     int32(quorum),
+					Rules: rules,
+				},
+			},
+		},
+		Identities: principals,
+	})
+}
+
+// countBidders counts the distinct buyers of the given bids, ineligible bids do not count
+// Buyers are identified by the PEM form of their certificate, like in EndAuction.
+func countBidders(bids []Bid) int {
+	buyers := make(map[string]bool)
+	for i := range bids {
+		if bids[i].Ineligible {
+			continue
+		}
+		buyerCertPem := certDerToPem(bids[i].Buyer)
+		if buyerCertPem == nil {
+			continue
+		}
+		buyers[*buyerCertPem] = true
+	}
+	return len(buyers)
+}
+
+// newAuctionSummary builds the summary of the current auction state
+// If the auction uses anonymous IDs, the certificates are replaced by their anonymous IDs.
+// The number of bidders is 0 while the auction hides its number of bids.
+func newAuctionSummary(auction *Auction, bids []Bid) *AuctionSummary {
+	if !isBidCountVisible(auction) {
+		bids = nil
+	}
+	summary := &AuctionSummary{
+		SchemaVersion:  auctionSummarySchemaVersion,
+		Name:           auction.Name,
+		Seller:         auction.Seller,
+		Status:         auction.Status,
+		Type:           auction.Type,
+		DirectBuyPrice: auction.DirectBuyPrice,
+		PriceScale:     auction.PriceScale,
+		EarliestClose:  auction.EarliestClose,
+		NumBidders:     countBidders(bids),
+		Result:         auction.Result,
+		Version:        auction.Version,
+		LastUpdated:    auction.LastUpdated,
+		Currency:       auction.Currency,
+		Description:    auction.Description,
+		MetadataURI:    auction.MetadataURI,
+		AnonymousIDs:   auction.AnonymousIDs,
+	}
+	if !auction.AnonymousIDs {
+		return summary
+	}
+
+	// The stored result must not be modified, so the summary gets a copy
+	summary.Seller = anonymousID(auction.Name, auction.Seller)
+	if auction.Result != nil {
+		result := *auction.Result
+		result.Winner = anonymousID(auction.Name, result.Winner)
+		result.Winners = make([][]byte, 0, len(auction.Result.Winners))
+		for _, winner := range auction.Result.Winners {
+			result.Winners = append(result.Winners, anonymousID(auction.Name, winner))
+		}
+		result.Charges = anonymizeCharges(auction.Name, auction.Result.Charges)
+		result.Refunds = anonymizeCharges(auction.Name, auction.Result.Refunds)
+		summary.Result = &result
+	}
+	return summary
+}
+
+// anonymizeCharges copies the charges with anonymous IDs instead of the bidder certificates
+func anonymizeCharges(auctionName string, charges []Charge) []Charge {
+	if charges == nil {
+		return nil
+	}
+	anonymizedCharges := make([]Charge, 0, len(charges))
+	for _, charge := range charges {
+		anonymizedCharges = append(anonymizedCharges, Charge{
+			Bidder: anonymousID(auctionName, charge.Bidder),
+			Amount: charge.Amount,
+		})
+	}
+	return anonymizedCharges
+}
+
+// newAuctionView creates the client-friendly view of an auction and its bids
+// The hidden commits are left out while the auction is open, so that the view cannot help anyone to adapt their bids.
+// The bids are left out completely while the auction hides its number of bids.
+func newAuctionView(auction *Auction, bids []Bid) *AuctionView {
+	if !isBidCountVisible(auction) {
+		bids = nil
+	}
+	view := &AuctionView{
+		Name:              auction.Name,
+		SellerPem:         certPemString(auction.Seller),
+		Status:            auction.Status,
+		Type:              auction.Type,
+		DirectBuyPrice:    auction.DirectBuyPrice,
+		PriceScale:        auction.PriceScale,
+		Currency:          auction.Currency,
+		ItemRef:           auction.ItemRef,
+		HasReserve:        auction.HasReserve,
+		ReservePrice:      auction.ReservePrice,
+		EarliestClose:     auction.EarliestClose,
+		AllowedMSPs:       auction.AllowedMSPs,
+		InvitedBidderPems: make([]string, 0, len(auction.InvitedBidders)),
+		Quantity:          auctionQuantity(auction),
+		MinDeposit:        auction.MinDeposit,
+		MinBidPrice:       auction.MinBidPrice,
+		MaxBidPrice:       auction.MaxBidPrice,
+		Unsealed:          auction.Unsealed,
+		Description:       auction.Description,
+		MetadataURI:       auction.MetadataURI,
+		WinnerPems:        []string{},
+		HammerPrice:       auction.HammerPrice,
+		DirectBuy:         false,
+		Version:           auction.Version,
+		Bids:              make([]BidView, 0, len(bids)),
+	}
+	for _, invitedBidder := range auction.InvitedBidders {
+		view.InvitedBidderPems = append(view.InvitedBidderPems, certPemString(invitedBidder))
+	}
+	result := auctionResult(auction)
+	for _, winner := range result.Winners {
+		view.WinnerPems = append(view.WinnerPems, certPemString(winner))
+	}
+	view.DirectBuy = result.DirectBuy
+	for i := range bids {
+		bid := &bids[i]
+		bidView := BidView{
+			ID:                bid.ID,
+			BidderFingerprint: "",
+			BidPrice:          bid.BidPrice,
+			HiddenCommit:      "",
+			Timestamp:         bid.Timestamp,
+			Ineligible:        bid.Ineligible,
+		}
+		// Scrubbed bids have no bidder anymore
+		if bid.Buyer != nil {
+			bidView.BidderFingerprint = certFingerprint(bid.Buyer)
+		}
+		if auction.Status != AuctionStatus(Open) {
+			bidView.HiddenCommit = hex.EncodeToString(bid.HiddenCommit)
+		}
+		view.Bids = append(view.Bids, bidView)
+	}
+	return view
+}
+
+// newBidBook lists the revealed bids of an auction by descending price, with anonymized buyers
+// Unrevealed and ineligible bids are left out. Auctions which redact the revealed prices get no bid book.
+func newBidBook(auction *Auction, bids []Bid) []BidBookEntry {
+	if auction.RedactRevealedPrices {
+		return nil
+	}
+	bidBook := []BidBookEntry{}
+	for i := range bids {
+		if bids[i].BidPrice == 0 || bids[i].Ineligible {
+			continue
+		}
+		bidBook = append(bidBook, BidBookEntry{
+			BuyerHash: anonymousID(auction.Name, bids[i].Buyer),
+			BidPrice:  bids[i].BidPrice,
+		})
+	}
+	sort.SliceStable(bidBook, func(i int, j int) bool {
+		if bidBook[i].BidPrice != bidBook[j].BidPrice {
+			return bidBook[i].BidPrice > bidBook[j].BidPrice
+		}
+		return bytes.Compare(bidBook[i].BuyerHash, bidBook[j].BuyerHash) < 0
+	})
+	return bidBook
+}
+
+// anonymousID computes the identifier which replaces a certificate in the events of an auction with anonymous IDs
+// It is the SHAKE256 hash of the auction name and the DER certificate, so the same client gets different IDs in different auctions.
+// The hash cannot be reversed, but anyone who knows a certificate can compute its ID.
+func anonymousID(auctionName string, derCert []byte) []byte {
+	if derCert == nil {
+		return nil
+	}
+	id := make([]byte, anonymousIDLength)
+	sha3.ShakeSum256(id, []byte(fmt.Sprintf("%s/%s\x00%s", anonymousIDFormat, auctionName, derCert)))
+	return id
+}
+
+// auctionMinSaltLength returns the minimum salt length in bytes of the auction
+// Auctions without a configured minimum, including those stored before it could be configured, use the default.
+func auctionMinSaltLength(auction *Auction) int {
+	if auction.MinSaltBytes == 0 {
+		return minSaltLength
+	}
+	return int(auction.MinSaltBytes)
+}
+
+// isBidderInvited checks if the auction allows the given client (DER certificate) to buy
+// Auctions without invited bidders are open to everyone.
+func isBidderInvited(auction *Auction, clientCert []byte) bool {
+	if len(auction.InvitedBidders) == 0 {
+		return true
+	}
+	for _, invitedBidder := range auction.InvitedBidders {
+		if reflect.DeepEqual(invitedBidder, clientCert) {
+			return true
+		}
+	}
+	return false
+}
+
+// auctionResult returns the result of an ended auction
+// Auctions which ended before the result was stored are treated as won by the highest bidder.
+func auctionResult(auction *Auction) *AuctionResult {
+	if auction.Result != nil {
+		if auction.Result.Winners == nil && auction.Result.Winner != nil {
+			// Stored before multi-unit auctions were supported
+			result := *auction.Result
+			result.Winners = [][]byte{result.Winner}
+			return &result
+		}
+		return auction.Result
+	}
+	winners := [][]byte{}
+	if auction.Winner != nil {
+		winners = append(winners, auction.Winner)
+	}
+	return &AuctionResult{
+		Winner:      auction.Winner,
+		Winners:     winners,
+		DirectBuy:   false,
+		HammerPrice: auction.HammerPrice,
+		Currency:    auction.Currency,
+	}
+}
+
+// auctionQuantity returns the number of units sold in the auction
+// Auctions stored before multi-unit auctions were supported sell a single unit.
+func auctionQuantity(auction *Auction) uint64 {
+	if auction.Quantity == 0 {
+		return 1
+	}
+	return auction.Quantity
+}
+
+// isAuctionWinner checks if the given buyer won the auction
+func isAuctionWinner(auction *Auction, buyer []byte) bool {
+	if buyer == nil {
+		return false
+	}
+	for _, winner := range auctionResult(auction).Winners {
+		if reflect.DeepEqual(winner, buyer) {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalQueryResult encodes a query result as a JSON string
+// Results containing byte slices (e.g. certificates) cannot be returned as structs, because contractapi validates
+// returned structs against a schema which expects byte slices to be integer arrays, while encoding/json produces base64 strings.
+// The returned JSON has the same format as the event payloads.
+func marshalQueryResult(result interface{}) (string, error) {
+	resultBin, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(resultBin), nil
+}
+
 // setAuctionSummaryEvent sets an event about the current auction status which can be received by contract users
 func setAuctionSummaryEvent(ctx contractapi.TransactionContextInterface, auctionSummary *AuctionSummary) error {
 	if auctionSummary == nil {
@@ -64,22 +1444,516 @@ func setAuctionSummaryEvent(ctx contractapi.TransactionContextInterface, auction
 	return ctx.GetStub().SetEvent(auctionKey(auctionSummary.Name), auctionSummaryBin)
 }
 
+// bidEventName gets the name of the event which is set when a bid is submitted to the given auction
+func bidEventName(auctionName string) string {
+	return fmt.Sprintf("bid %s", auctionName)
+}
+
+// purgeEventName gets the name of the event which is set when an ended auction is purged
+func purgeEventName(auctionName string) string {
+	return fmt.Sprintf("purge %s", auctionName)
+}
+
+// setPurgeEvent sets the last summary of a purged auction as event, it is the tombstone of the auction
+func setPurgeEvent(ctx contractapi.TransactionContextInterface, auctionSummary *AuctionSummary) error {
+	if auctionSummary == nil {
+		return fmt.Errorf("auctionSummary cannot be nil")
+	}
+	auctionSummaryBin, err := json.Marshal(auctionSummary)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(purgeEventName(auctionSummary.Name), auctionSummaryBin)
+}
+
+// newBidEvent creates the event of a submitted bid with the number of bids so far
+// The buyer is anonymized and the number of bids is left out, if the auction asks for it.
+func newBidEvent(auction *Auction, bid *Bid, numBids int) *BidEvent {
+	eventBuyer := bid.Buyer
+	if auction.AnonymousIDs {
+		eventBuyer = anonymousID(auction.Name, bid.Buyer)
+	}
+	if auction.HideBidCount {
+		numBids = 0
+	}
+	return &BidEvent{
+		AuctionName: auction.Name,
+		BidID:       bid.ID,
+		Buyer:       eventBuyer,
+		NumBids:     numBids,
+	}
+}
+
+// setBidEvent sets an event about a new bid which can be received by contract users
+func setBidEvent(ctx contractapi.TransactionContextInterface, bidEvent *BidEvent) error {
+	if bidEvent == nil {
+		return fmt.Errorf("bidEvent cannot be nil")
+	}
+	bidEventBin, err := json.Marshal(bidEvent)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(bidEventName(bidEvent.AuctionName), bidEventBin)
+}
+
+// revealEventName gets the name of the event which is set when bids of the given auction are revealed
+func revealEventName(auctionName string) string {
+	return fmt.Sprintf("reveal %s", auctionName)
+}
+
+// setRevealEvent sets an event about revealed bids which can be received by contract users
+// The buyer is anonymized and the prices are left out, if the auction asks for it.
+func setRevealEvent(ctx contractapi.TransactionContextInterface, auction *Auction, buyer []byte, prices []uint64) error {
+	revealEvent := RevealEvent{
+		AuctionName: auction.Name,
+		Buyer:       buyer,
+		NumRevealed: len(prices),
+		Prices:      prices,
+	}
+	if auction.AnonymousIDs {
+		revealEvent.Buyer = anonymousID(auction.Name, buyer)
+	}
+	if auction.RedactRevealedPrices {
+		revealEvent.Prices = nil
+	}
+	revealEventBin, err := json.Marshal(revealEvent)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(revealEventName(auction.Name), revealEventBin)
+}
+
+// strikeEventName gets the name of the event which is set when the seller strikes the bids of a bidder
+func strikeEventName(auctionName string) string {
+	return fmt.Sprintf("strike %s", auctionName)
+}
+
+// setStrikeEvent sets an event about struck bids which can be received by contract users
+// The buyer is anonymized, if the auction asks for it.
+func setStrikeEvent(ctx contractapi.TransactionContextInterface, auction *Auction, buyer []byte, numStruck int) error {
+	strikeEvent := StrikeEvent{
+		AuctionName: auction.Name,
+		Buyer:       buyer,
+		NumStruck:   numStruck,
+	}
+	if auction.AnonymousIDs {
+		strikeEvent.Buyer = anonymousID(auction.Name, buyer)
+	}
+	strikeEventBin, err := json.Marshal(strikeEvent)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(strikeEventName(auction.Name), strikeEventBin)
+}
+
+// getTxTimestamp returns the transaction timestamp in Unix seconds
+// It is set by the client and is the same for all endorsers, so it can be used deterministically.
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (int64, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, err
+	}
+	return txTimestamp.GetSeconds(), nil
+}
+
+// Parameters of the bid commitment scheme implemented by hashBid
+const (
+	bidCommitmentFormat = "shake256-cert-price-salt/v1" // Changes whenever the preimage layout changes
+	bidCommitmentLength = 64                            // Length of the SHAKE256 output in bytes
+	minSaltLength       = 64                            // Minimum salt length in bytes
+	anonymousIDFormat   = "anonymous-id/v1"             // Domain separation of anonymous IDs from other hashes
+	anonymousIDLength   = 32                            // Length of anonymous IDs in bytes
+	bidSignatureFormat  = "bid-signature/v1"            // Domain separation of bid signatures from other signatures of the key
+)
+
+// maxCommitDifficulty limits the number of leading zero bits an auction can require of the hidden commits
+// A client needs about 16 million hash computations on average to find a salt for this difficulty.
+const maxCommitDifficulty = 24
+
+// maxAuctionSummaries limits the number of summaries returned by GetAllAuctionSummaries
+const maxAuctionSummaries = 100
+
+// Limits of the length of auction names in bytes
+const (
+	defaultMaxAuctionNameLength = 128  // Limit of new auction names until the admin configures another one
+	upperMaxAuctionNameLength   = 1024 // Highest limit the admin can configure
+)
+
+// Parties which can pay the platform fee of an auction
+const (
+	feePayerSeller = "seller" // The fee is deducted from the payments to the seller
+	feePayerBuyer  = "buyer"  // Every buyer pays the fee on top of what they owe
+)
+
+// maxFeeBps is the highest platform fee in basis points, it takes all of the seller's proceeds
+const maxFeeBps = 10000
+
+// Limits of the item information, which is stored in the auction and copied into every summary
+const (
+	maxDescriptionLength = 1024 // Maximum length of the item description in bytes
+	maxMetadataURILength = 512  // Maximum length of the metadata URI in bytes
+)
+
+// allowedCurrencies are the units in which the prices of an auction can be given
+// Off-chain systems rely on these names, so free-form units are not accepted.
+var allowedCurrencies = []string{"USD", "EUR", "GBP", "CHF", "JPY", "tokens"}
+
+// checkCurrency returns an error if the currency is not allowed, the empty currency leaves the unit unspecified
+func checkCurrency(currency string) error {
+	if currency == "" {
+		return nil
+	}
+	for _, allowedCurrency := range allowedCurrencies {
+		if currency == allowedCurrency {
+			return nil
+		}
+	}
+	return fmt.Errorf("currency %q is not supported, use one of %s", currency, strings.Join(allowedCurrencies, ", "))
+}
+
+// auctionSummarySchemaVersion is the version of the AuctionSummary layout, it is increased whenever fields change incompatibly
+const auctionSummarySchemaVersion = 1
+
+// auctionOutcome is the result of a closed auction before the tie-break
+// EndAuction chooses the remaining winners among the candidates pseudo-randomly, PreviewResult reports the candidates.
+type auctionOutcome struct {
+	winners            [][]byte // Bidders who win for sure, ordered by descending bid price
+	winnerBids         []uint64 // Highest bid of each of the winners
+	candidates         [][]byte // Bidders with the lowest winning price, among whom the remaining units are distributed
+	numCandidateUnits  uint64   // Number of units which go to the candidates
+	lowestWinningPrice uint64
+	hammerPrice        uint64
+	charges            []Charge // What every bidder pays in an all-pay auction
+	winnersPayOwnBids  bool     // Set for multi-unit first-price auctions, whose winners are charged individually
+	currency           string
+}
+
+// computeAuctionOutcome determines the winners and the hammer price of a closed auction from its revealed bids
+// It does not access the world state, so that EndAuction and PreviewResult compute the same outcome.
+func computeAuctionOutcome(auction *Auction, bids []Bid) (*auctionOutcome, error) {
+	// Build a mapping from the buyer (PEM certificate) to their highest bid
+	buyerToBid := make(map[string]uint64)
+	for i := range bids {
+		bid := &bids[i]
+		if bid.BidPrice == 0 {
+			return nil, newAuctionError(ErrCodeInvalidStatus, "not all bids are revealed yet")
+		}
+		// Bids revealed with a price outside the range do not compete
+		if bid.Ineligible {
+			continue
+		}
+		buyerCertPem := certDerToPem(bid.Buyer)
+		if buyerCertPem == nil {
+			return nil, fmt.Errorf("could not convert certificate from DER to PEM format")
+		}
+		prevBid, exists := buyerToBid[*buyerCertPem]
+		if !exists || bid.BidPrice > prevBid {
+			buyerToBid[*buyerCertPem] = bid.BidPrice
+		}
+	}
+
+	type BidPriceBuyerPair struct {
+		BidPrice uint64
+		Buyer    []byte
+	}
+
+	// Convert map to (BidPrice, Buyer) slice
+	bidPriceToBuyer := make([]BidPriceBuyerPair, 0, len(buyerToBid))
+
+	for buyer, bidPrice := range buyerToBid {
+		buyerCertDer := certPemToDer(buyer)
+		if buyerCertDer == nil {
+			return nil, fmt.Errorf("the certificate is not a valid PEM encoded X.509 certificate")
+		}
+		bidPriceToBuyer = append(bidPriceToBuyer, BidPriceBuyerPair{
+			BidPrice: bidPrice,
+			Buyer:    buyerCertDer,
+		})
+	}
+
+	// Sort bidders by descending bid price
+	// Bidders with the same price are ordered by their certificate, so that all endorsers get the same order
+	sort.Slice(bidPriceToBuyer, func(i int, j int) bool {
+		if bidPriceToBuyer[i].BidPrice != bidPriceToBuyer[j].BidPrice {
+			return bidPriceToBuyer[i].BidPrice > bidPriceToBuyer[j].BidPrice
+		}
+		return bytes.Compare(bidPriceToBuyer[i].Buyer, bidPriceToBuyer[j].Buyer) < 0
+	})
+
+	quantity := auctionQuantity(auction)
+	outcome := &auctionOutcome{
+		winners:            [][]byte{},
+		winnerBids:         []uint64{},
+		candidates:         [][]byte{},
+		numCandidateUnits:  0,
+		lowestWinningPrice: 0,
+		hammerPrice:        0,
+		charges:            nil,
+		winnersPayOwnBids:  auction.Type == AuctionType(FirstPrice) && quantity > 1,
+		currency:           auction.Currency,
+	}
+
+	// In an all-pay auction, every bidder pays their own bid, even if it is below the reserve price
+	if auction.Type == AuctionType(AllPay) {
+		outcome.charges = make([]Charge, 0, len(bidPriceToBuyer))
+		for i := range bidPriceToBuyer {
+			outcome.charges = append(outcome.charges, Charge{
+				Bidder: bidPriceToBuyer[i].Buyer,
+				Amount: bidPriceToBuyer[i].BidPrice,
+			})
+		}
+	}
+
+	// Bids below the reserve price cannot win
+	if auction.HasReserve {
+		eligibleBidders := 0
+		for eligibleBidders < len(bidPriceToBuyer) && bidPriceToBuyer[eligibleBidders].BidPrice >= auction.ReservePrice {
+			eligibleBidders += 1
+		}
+		bidPriceToBuyer = bidPriceToBuyer[:eligibleBidders]
+	}
+
+	// No (eligible) bids submitted => no winner
+	if len(bidPriceToBuyer) == 0 {
+		return outcome, nil
+	}
+
+	// The highest bidders win one unit each
+	numberOfWinners := uint64(len(bidPriceToBuyer))
+	if numberOfWinners > quantity {
+		numberOfWinners = quantity
+	}
+	lowestWinningPrice := bidPriceToBuyer[numberOfWinners-1].BidPrice
+	outcome.lowestWinningPrice = lowestWinningPrice
+
+	// Determine hammer price, all winners pay the highest losing bid
+	// The reserve price acts like a bid of the seller, so if all eligible bidders win, they pay the reserve price.
+	// Without a reserve, or if the seller asked for it, they pay the lowest winning bid, e.g. a single bidder pays their own bid.
+	outcome.hammerPrice = lowestWinningPrice
+	if uint64(len(bidPriceToBuyer)) > quantity {
+		outcome.hammerPrice = bidPriceToBuyer[quantity].BidPrice
+	} else if auction.HasReserve && !auction.SoleBidderPaysOwnBid {
+		outcome.hammerPrice = auction.ReservePrice
+	}
+
+	// In first-price and all-pay auctions, the winners pay their own bids, so the hammer price is the lowest of them
+	if auction.Type != AuctionType(Vickrey) {
+		outcome.hammerPrice = lowestWinningPrice
+	}
+
+	// Bidders above the lowest winning price win for sure
+	// The winners are ordered by descending bid price, so they are the places of the auction.
+	firstCandidate := 0
+	for firstCandidate < len(bidPriceToBuyer) && bidPriceToBuyer[firstCandidate].BidPrice > lowestWinningPrice {
+		outcome.winners = append(outcome.winners, bidPriceToBuyer[firstCandidate].Buyer)
+		outcome.winnerBids = append(outcome.winnerBids, bidPriceToBuyer[firstCandidate].BidPrice)
+		firstCandidate += 1
+	}
+
+	// The remaining units go to the bidders with the lowest winning price
+	for i := firstCandidate; i < len(bidPriceToBuyer) && bidPriceToBuyer[i].BidPrice == lowestWinningPrice; i++ {
+		outcome.candidates = append(outcome.candidates, bidPriceToBuyer[i].Buyer)
+	}
+	outcome.numCandidateUnits = numberOfWinners - uint64(len(outcome.winners))
+
+	return outcome, nil
+}
+
+// result builds the auction result from the outcome and the winners chosen among the candidates
+func (outcome *auctionOutcome) result(chosenCandidates [][]byte) *AuctionResult {
+	winners := make([][]byte, 0, len(outcome.winners)+len(chosenCandidates))
+	winners = append(winners, outcome.winners...)
+	winners = append(winners, chosenCandidates...)
+	result := &AuctionResult{
+		Winner:      nil,
+		Winners:     winners,
+		HammerPrice: outcome.hammerPrice,
+		DirectBuy:   false,
+		Charges:     outcome.charges,
+		Currency:    outcome.currency,
+	}
+	if len(winners) != 0 {
+		result.Winner = winners[0]
+	}
+
+	// The winners of a multi-unit first-price auction pay their own bids
+	if outcome.winnersPayOwnBids && len(winners) != 0 {
+		result.Charges = make([]Charge, 0, len(winners))
+		for i, winner := range winners {
+			amount := outcome.lowestWinningPrice
+			if i < len(outcome.winnerBids) {
+				amount = outcome.winnerBids[i]
+			}
+			result.Charges = append(result.Charges, Charge{
+				Bidder: winner,
+				Amount: amount,
+			})
+		}
+	}
+	return result
+}
+
+// tieBreakIndex deterministically chooses an index in [0, numberOfCandidates) from the seed
+// The seed is absorbed into SHAKE256 and the output stream is read as big endian 64 bit integers.
+// To avoid the modulo bias, rejection sampling is used: values in the incomplete last interval
+// [2^64 - (2^64 mod n), 2^64) are discarded and the next 8 bytes are read, until a value below that bound is found.
+// The accepted value modulo n is uniformly distributed. Each attempt succeeds with a probability of more than 1/2.
+func tieBreakIndex(seed []byte, numberOfCandidates uint64) (uint64, error) {
+	if numberOfCandidates == 0 {
+		return 0, fmt.Errorf("there must be at least one candidate")
+	}
+	shake := sha3.NewShake256()
+	_, errShakeWrite := shake.Write(seed)
+	if errShakeWrite != nil {
+		return 0, fmt.Errorf("failed to write data to SHAKE: %v", errShakeWrite)
+	}
+	// Number of values at the top of the 64 bit range which must be rejected: 2^64 mod n
+	rejected := (math.MaxUint64%numberOfCandidates + 1) % numberOfCandidates
+	randomBytes := [8]byte{}
+	for {
+		_, errShakeRead := shake.Read(randomBytes[:])
+		if errShakeRead != nil {
+			return 0, fmt.Errorf("failed to read data from SHAKE: %v", errShakeRead)
+		}
+		random := binary.BigEndian.Uint64(randomBytes[:])
+		if random <= math.MaxUint64-rejected {
+			return random % numberOfCandidates, nil
+		}
+	}
+}
+
+// tieBreakSelection pseudo-randomly chooses k of n candidates and returns their indices in the order of selection
+// The first choice is tieBreakIndex(seed, n), so a single choice is the same as a plain tie-break.
+// Further choices use the seed extended by the choice number.
+func tieBreakSelection(seed []byte, n uint64, k uint64) ([]uint64, error) {
+	if k > n {
+		return nil, fmt.Errorf("cannot choose %d of %d candidates", k, n)
+	}
+	indices := make([]uint64, n)
+	for i := range indices {
+		indices[i] = uint64(i)
+	}
+	for j := uint64(0); j < k; j++ {
+		choiceSeed := seed
+		if j > 0 {
+			choiceSeed = []byte(fmt.Sprintf("%s/%d", seed, j))
+		}
+		choice, err := tieBreakIndex(choiceSeed, n-j)
+		if err != nil {
+			return nil, err
+		}
+		indices[j], indices[j+choice] = indices[j+choice], indices[j]
+	}
+	return indices[:k], nil
+}
+
+// bidSignatureMessage builds the message which a bidder signs for BidSigned
+// It contains the auction name, so that a signed commitment cannot be replayed in another auction.
+func bidSignatureMessage(auctionName string, hiddenCommit []byte) []byte {
+	message := []byte(bidSignatureFormat + "/" + auctionName + "\x00")
+	return append(message, hiddenCommit...)
+}
+
+// verifyBidSignature checks the ECDSA signature of a bid against the public key of the bidder's certificate
+func verifyBidSignature(clientCert *x509.Certificate, auctionName string, hiddenCommit []byte, signature []byte) error {
+	publicKey, ok := clientCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("only ECDSA keys are supported")
+	}
+	digest := sha256.Sum256(bidSignatureMessage(auctionName, hiddenCommit))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+		return fmt.Errorf("the signature does not match the hidden commit")
+	}
+	return nil
+}
+
 // hashBid hashes a bid
 // It takes a random salt and the client's ID (X.509 certificate) into account
 func hashBid(clientCert *x509.Certificate, bidPrice uint64, salt []byte) ([]byte, error) {
 	shake := sha3.NewShake256()
-	bidPriceBytes := [8]byte{}
-	binary.BigEndian.PutUint64(bidPriceBytes[:], bidPrice)
-	for _, data := range [][]byte{clientCert.Raw, bidPriceBytes[:], salt} {
+	for _, data := range [][]byte{clientCert.Raw, uint64ToBigEndian(bidPrice), salt} {
 		_, errShakeWrite := shake.Write(data)
 		if errShakeWrite != nil {
 			return nil, fmt.Errorf("failed to write data to SHAKE: %v", errShakeWrite)
 		}
 	}
-	hash := make([]byte, 64)
+	hash := make([]byte, bidCommitmentLength)
 	_, errShakeRead := shake.Read(hash)
 	if errShakeRead != nil {
 		return nil, fmt.Errorf("failed to read data from SHAKE: %v", errShakeRead)
 	}
 	return hash, nil
 }
+
+// auctionInvariantViolations checks the consistency of an auction and its bids
+// It returns a description of every violated invariant, so an empty list means the state is healthy.
+func auctionInvariantViolations(auction *Auction, bids []Bid) []string {
+	violations := []string{}
+
+	// Only ended auctions have a result
+	// The hammer price of an unfinished English auction is the standing high bid.
+	ended := auction.Status == AuctionStatus(Ended)
+	liveHammerPrice := auction.Type == AuctionType(English) && !ended
+	if !ended && (auction.Result != nil || auction.Winner != nil || (auction.HammerPrice != 0 && !liveHammerPrice)) {
+		violations = append(violations, fmt.Sprintf("auction is %s but has a result", auction.Status))
+	}
+	if auction.Winner == nil && auction.HammerPrice != 0 && !liveHammerPrice {
+		violations = append(violations, "auction has a hammer price but no winner")
+	}
+	if auction.Result != nil &&
+		(!reflect.DeepEqual(auction.Result.Winner, auction.Winner) || auction.Result.HammerPrice != auction.HammerPrice) {
+		violations = append(violations, "stored result does not match the winner and hammer price")
+	}
+
+	// Option fields
+	if auction.HasReserve && auction.DirectBuyPrice != 0 && auction.ReservePrice > auction.DirectBuyPrice {
+		violations = append(violations, "reserve price is above the direct buy price")
+	}
+	if auction.EarliestClose < 0 {
+		violations = append(violations, "earliest close time is negative")
+	}
+
+	// Bids
+	scrubbed := ended && auction.ScrubLosersAfterEnd
+	directBuy := auction.Result != nil && auction.Result.DirectBuy
+	openBids := takesOpenBids(auction)
+	winnerBids := make(map[string]uint64)
+	for i := range bids {
+		bid := &bids[i]
+		isWinner := ended && isAuctionWinner(auction, bid.Buyer)
+		if !openBids && !(scrubbed && !isWinner) && len(bid.HiddenCommit) != bidCommitmentLength {
+			violations = append(violations, fmt.Sprintf("bid %s has a hidden commit of %d bytes", bid.ID, len(bid.HiddenCommit)))
+		}
+		if !(scrubbed && !isWinner) && len(bid.Buyer) == 0 {
+			violations = append(violations, fmt.Sprintf("bid %s has no buyer", bid.ID))
+		}
+		if !openBids && auction.Status == AuctionStatus(Open) && bid.BidPrice != 0 {
+			violations = append(violations, fmt.Sprintf("bid %s is revealed while the auction is open", bid.ID))
+		}
+		if ended && !directBuy && bid.BidPrice == 0 {
+			violations = append(violations, fmt.Sprintf("bid %s is not revealed although the auction ended", bid.ID))
+		}
+		if isWinner && !bid.Ineligible && bid.BidPrice > winnerBids[string(bid.Buyer)] {
+			winnerBids[string(bid.Buyer)] = bid.BidPrice
+		}
+	}
+
+	// The winners of a bidding round must be among the bidders and pay at most their bid
+	// The winner of a Dutch auction accepted the clock price without a bid.
+	if ended && !directBuy && auction.Type != AuctionType(Dutch) {
+		winners := auctionResult(auction).Winners
+		if uint64(len(winners)) > auctionQuantity(auction) {
+			violations = append(violations, "auction has more winners than units")
+		}
+		for _, winner := range winners {
+			winnerBid, winnerFound := winnerBids[string(winner)]
+			if !winnerFound {
+				violations = append(violations, "winner is not among the bidders")
+			} else if auction.HammerPrice > winnerBid {
+				violations = append(violations, "hammer price is above the winning bid")
+			}
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,830 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"golang.org/x/crypto/sha3"
+)
+
+// checkErrorCode fails the test unless err has the given code, an empty code expects no error
+func checkErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if code == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), code+": ") {
+		t.Fatalf("expected an error with code %s, got %v", code, err)
+	}
+}
+
+// newTestCerts returns the certificates of n new clients
+func newTestCerts(t *testing.T, n int) []*x509.Certificate {
+	t.Helper()
+	certs := make([]*x509.Certificate, n)
+	for i := range certs {
+		certs[i] = newMockIdentity(t, "bidder", "Org1MSP").cert
+	}
+	return certs
+}
+
+func TestGetAuctionCache(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+
+	stub, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		first, err := getAuction(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		second, err := getAuction(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		if first == nil || first != second {
+			t.Fatal("the second read did not return the cached auction")
+		}
+
+		// A deleted auction is not found anymore, although the world state still has it until the commit
+		err = deleteAuction(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		deleted, err := getAuction(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		if deleted != nil {
+			t.Fatal("the deleted auction was still found")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.numGetState != 1 {
+		t.Fatalf("expected a single read of the world state, got %d", stub.numGetState)
+	}
+}
+
+func TestValidateAuctionName(t *testing.T) {
+	tests := []struct {
+		name        string
+		auctionName string
+		wantErr     bool
+	}{
+		{"simple", "auction1", false},
+		{"spaces and umlauts", "Auktion für Bücher", false},
+		{"maximum length", strings.Repeat("a", defaultMaxAuctionNameLength), false},
+		{"empty", "", true},
+		{"too long", strings.Repeat("a", defaultMaxAuctionNameLength+1), true},
+		{"invalid UTF-8", "auction\xff", true},
+		{"composite key delimiter", "auction\x00name", true},
+		{"end of a range", "auction\U0010FFFF", true},
+		{"control character", "auction\n", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateAuctionName(test.auctionName, defaultMaxAuctionNameLength)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("validateAuctionName(%q) = %v, want error: %v", test.auctionName, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDutchClockPrice(t *testing.T) {
+	auction := Auction{StartPrice: 100, PriceDecrement: 15, DecrementInterval: 60, ClockStart: 1000}
+	withReserve := auction
+	withReserve.HasReserve = true
+	withReserve.ReservePrice = 50
+	withoutInterval := auction
+	withoutInterval.DecrementInterval = 0
+	tests := []struct {
+		name      string
+		auction   Auction
+		timestamp int64
+		want      uint64
+	}{
+		{"before the start", auction, 900, 100},
+		{"at the start", auction, 1000, 100},
+		{"within the first interval", auction, 1059, 100},
+		{"after one interval", auction, 1060, 85},
+		{"after six intervals", auction, 1360, 10},
+		{"below zero", auction, 1420, 0},
+		{"long after the start", auction, math.MaxInt64, 0},
+		{"above the reserve", withReserve, 1120, 70},
+		{"last price above the reserve", withReserve, 1180, 55},
+		{"below the reserve", withReserve, 1240, 50},
+		{"without an interval", withoutInterval, 5000, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := dutchClockPrice(&test.auction, test.timestamp); got != test.want {
+				t.Fatalf("dutchClockPrice at %d = %d, want %d", test.timestamp, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEnglishMinimumBid(t *testing.T) {
+	tests := []struct {
+		name     string
+		auction  Auction
+		want     uint64
+		wantCode string
+	}{
+		{"first bid", Auction{}, 1, ""},
+		{"first bid with a minimum", Auction{MinBidPrice: 20}, 20, ""},
+		{"without an increment", Auction{HammerPrice: 30}, 31, ""},
+		{"with an increment", Auction{HammerPrice: 30, BidIncrement: 5, MinBidPrice: 20}, 35, ""},
+		{"at the maximum", Auction{HammerPrice: math.MaxUint64 - 5, BidIncrement: 5}, math.MaxUint64, ""},
+		{"overflow", Auction{HammerPrice: math.MaxUint64 - 4, BidIncrement: 5}, 0, ErrCodeInvalidStatus},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := englishMinimumBid(&test.auction)
+			checkErrorCode(t, err, test.wantCode)
+			if got != test.want {
+				t.Fatalf("englishMinimumBid = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTieBreakIndex(t *testing.T) {
+	// The expected indices were computed independently from the SHAKE256 output
+	tests := []struct {
+		name    string
+		seed    string
+		n       uint64
+		want    uint64
+		wantErr bool
+	}{
+		{"single candidate", "seed", 1, 0, false},
+		{"two candidates", "seed", 2, 0, false},
+		{"three candidates", "seed", 3, 2, false},
+		{"transaction seed", "auction1/tx1", 7, 3, false},
+		{"empty seed", "", 10, 3, false},
+		// Almost half of the 64 bit values are rejected, the seed needs three rejections
+		{"rejection sampling", "a", 1<<63 + 1, 8317044053435943844, false},
+		{"no candidates", "seed", 0, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := tieBreakIndex([]byte(test.seed), test.n)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Fatalf("tieBreakIndex(%q, %d) = %d, want %d", test.seed, test.n, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTieBreakSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       uint64
+		k       uint64
+		wantErr bool
+	}{
+		{"none", 5, 0, false},
+		{"one", 5, 1, false},
+		{"some", 5, 3, false},
+		{"all", 5, 5, false},
+		{"too many", 5, 6, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seed := []byte("auction1/tx1")
+			got, err := tieBreakSelection(seed, test.n, test.k)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.wantErr {
+				return
+			}
+			if uint64(len(got)) != test.k {
+				t.Fatalf("expected %d indices, got %v", test.k, got)
+			}
+			chosen := make(map[uint64]bool)
+			for _, index := range got {
+				if index >= test.n || chosen[index] {
+					t.Fatalf("invalid selection %v", got)
+				}
+				chosen[index] = true
+			}
+			// A single choice is a plain tie-break
+			first, err := tieBreakIndex(seed, test.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.k != 0 && got[0] != first {
+				t.Fatalf("the first choice %d differs from tieBreakIndex %d", got[0], first)
+			}
+			again, err := tieBreakSelection(seed, test.n, test.k)
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Fatal("the selection is not deterministic")
+			}
+		})
+	}
+}
+
+func TestComputeAuctionOutcome(t *testing.T) {
+	certs := newTestCerts(t, 4)
+	type testBid struct {
+		bidder int
+		price  uint64
+	}
+	tests := []struct {
+		name           string
+		auction        Auction
+		bids           []testBid
+		wantCode       string
+		wantHammer     uint64
+		wantWinners    []int // Bidders who win for sure
+		wantCandidates int   // Number of bidders who tie for the remaining units
+		wantCharges    map[int]uint64
+	}{
+		{"no bids", Auction{}, nil, "", 0, nil, 0, nil},
+		{"unrevealed bid", Auction{}, []testBid{{0, 10}, {1, 0}}, ErrCodeInvalidStatus, 0, nil, 0, nil},
+		{"second price", Auction{}, []testBid{{0, 10}, {1, 40}, {2, 20}}, "", 20, nil, 1, nil},
+		{"highest bid of a bidder counts", Auction{}, []testBid{{0, 50}, {1, 40}, {0, 10}}, "", 40, nil, 1, nil},
+		{"single bidder pays own bid", Auction{}, []testBid{{0, 30}}, "", 30, nil, 1, nil},
+		{"first price", Auction{Type: AuctionType(FirstPrice)}, []testBid{{0, 10}, {1, 40}, {2, 20}}, "", 40, nil, 1, nil},
+		{"reserve price", Auction{HasReserve: true, ReservePrice: 25}, []testBid{{0, 10}, {1, 40}, {2, 20}}, "", 25, nil, 1, nil},
+		{"reserve of zero", Auction{HasReserve: true}, []testBid{{0, 30}}, "", 0, nil, 1, nil},
+		{"sole bidder pays own bid", Auction{HasReserve: true, ReservePrice: 25, SoleBidderPaysOwnBid: true}, []testBid{{0, 10}, {1, 40}}, "", 40, nil, 1, nil},
+		{"reserve not met", Auction{HasReserve: true, ReservePrice: 50}, []testBid{{0, 10}, {1, 40}}, "", 0, nil, 0, nil},
+		{"two units", Auction{Quantity: 2}, []testBid{{0, 10}, {1, 40}, {2, 20}}, "", 10, []int{1}, 1, nil},
+		{"more units than bidders", Auction{Quantity: 5}, []testBid{{0, 10}, {1, 40}}, "", 10, []int{1}, 1, nil},
+		{"tie", Auction{}, []testBid{{0, 40}, {1, 40}, {2, 10}}, "", 40, nil, 2, nil},
+		{"tie for the last unit", Auction{Quantity: 2}, []testBid{{0, 40}, {1, 30}, {2, 30}, {3, 10}}, "", 30, []int{0}, 2, nil},
+		{"all-pay", Auction{Type: AuctionType(AllPay), HasReserve: true, ReservePrice: 15}, []testBid{{0, 10}, {1, 40}, {2, 20}}, "", 40, nil, 1, map[int]uint64{0: 10, 1: 40, 2: 20}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bids := []Bid{}
+			for _, bid := range test.bids {
+				bids = append(bids, Bid{Buyer: certs[bid.bidder].Raw, BidPrice: bid.price})
+			}
+			outcome, err := computeAuctionOutcome(&test.auction, bids)
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil {
+				return
+			}
+			if outcome.hammerPrice != test.wantHammer {
+				t.Fatalf("hammer price %d, want %d", outcome.hammerPrice, test.wantHammer)
+			}
+			wantWinners := [][]byte{}
+			for _, winner := range test.wantWinners {
+				wantWinners = append(wantWinners, certs[winner].Raw)
+			}
+			if !reflect.DeepEqual(outcome.winners, wantWinners) {
+				t.Fatalf("got %d winners, want %v", len(outcome.winners), test.wantWinners)
+			}
+			if len(outcome.candidates) != test.wantCandidates {
+				t.Fatalf("got %d candidates, want %d", len(outcome.candidates), test.wantCandidates)
+			}
+			if outcome.numCandidateUnits > uint64(len(outcome.candidates)) {
+				t.Fatalf("%d units for %d candidates", outcome.numCandidateUnits, len(outcome.candidates))
+			}
+			if len(outcome.charges) != len(test.wantCharges) {
+				t.Fatalf("got %d charges, want %d", len(outcome.charges), len(test.wantCharges))
+			}
+			for bidder, amount := range test.wantCharges {
+				found := false
+				for _, charge := range outcome.charges {
+					if reflect.DeepEqual(charge.Bidder, certs[bidder].Raw) {
+						found = charge.Amount == amount
+					}
+				}
+				if !found {
+					t.Fatalf("bidder %d is not charged %d", bidder, amount)
+				}
+			}
+		})
+	}
+}
+
+func TestRevealBid(t *testing.T) {
+	network := newTestNetwork(t)
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	other := newMockIdentity(t, "other", "Org1MSP")
+	bid := newTestBid(t, bidder, 30)
+	hiddenCommit, err := hex.DecodeString(bid.hiddenCommit(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltHex := hex.EncodeToString(bid.salt)
+
+	tests := []struct {
+		name           string
+		auction        Auction
+		bids           []Bid
+		client         *mockIdentity
+		price          uint64
+		saltHex        string
+		wantCode       string
+		wantIneligible bool
+	}{
+		{"matching", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, saltHex, "", false},
+		{"wrong price", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 31, saltHex, ErrCodeBidMismatch, false},
+		{"wrong salt", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, saltHex[2:] + "00", ErrCodeBidMismatch, false},
+		{"other bidder", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, other, 30, saltHex, ErrCodeBidMismatch, false},
+		{"already revealed", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit, BidPrice: 30}}, bidder, 30, saltHex, ErrCodeAlreadyRevealed, false},
+		{"zero price", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 0, saltHex, ErrCodeInvalidArgument, false},
+		{"below the minimum", Auction{MinBidPrice: 40}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, saltHex, "", true},
+		{"above the maximum", Auction{MaxBidPrice: 20}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, saltHex, "", true},
+		{"salt not hex", Auction{}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, "xyz", ErrCodeInvalidArgument, false},
+		{"salt too short", Auction{MinSaltBytes: minSaltLength + 1}, []Bid{{Buyer: bidder.cert.Raw, HiddenCommit: hiddenCommit}}, bidder, 30, saltHex, ErrCodeInvalidArgument, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.auction.Name = "auction1"
+			stub, err := network.simulate(test.client, func(ctx contractapi.TransactionContextInterface) error {
+				return revealBid(ctx, &test.auction, test.bids, test.client.cert, test.price, test.saltHex)
+			})
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil {
+				return
+			}
+			// The revealed bid is updated in the slice and saved
+			if test.bids[0].BidPrice != test.price {
+				t.Fatal("the bid in the slice was not revealed")
+			}
+			// A committed price outside the range is revealed, but cannot win
+			if test.bids[0].Ineligible != test.wantIneligible {
+				t.Fatalf("expected the bid to be ineligible: %v, got %v", test.wantIneligible, test.bids[0].Ineligible)
+			}
+			if len(stub.writes) != 1 {
+				t.Fatalf("expected one write, got %d", len(stub.writes))
+			}
+		})
+	}
+}
+
+func TestSettleDeposits(t *testing.T) {
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	winner := newMockIdentity(t, "winner", "Org1MSP")
+	loser := newMockIdentity(t, "loser", "Org1MSP")
+	platform := newMockIdentity(t, "platform", "Org1MSP")
+	bidders := []*mockIdentity{winner, loser}
+	vickreyResult := &AuctionResult{Winner: winner.cert.Raw, Winners: [][]byte{winner.cert.Raw}, HammerPrice: 30}
+	allPayResult := &AuctionResult{
+		Winner:      winner.cert.Raw,
+		Winners:     [][]byte{winner.cert.Raw},
+		HammerPrice: 30,
+		Charges:     []Charge{{Bidder: winner.cert.Raw, Amount: 30}, {Bidder: loser.cert.Raw, Amount: 20}},
+	}
+
+	tests := []struct {
+		name         string
+		minDeposit   uint64
+		feeBps       uint16
+		feePayer     string
+		result       *AuctionResult
+		balances     []uint64 // Balances of the winner and the loser besides their deposits
+		wantRefunds  []uint64 // Refunds of the winner and the loser, 0 means none
+		wantBalances []uint64
+		wantSeller   uint64
+		wantFee      uint64
+	}{
+		{"no deposits", 0, 0, "", vickreyResult, []uint64{50, 50}, []uint64{0, 0}, []uint64{50, 50}, 0, 0},
+		{"rest paid from the balance", 10, 0, "", vickreyResult, []uint64{50, 0}, []uint64{0, 10}, []uint64{30, 10}, 30, 0},
+		{"deposit covers the price", 40, 0, "", vickreyResult, []uint64{0, 0}, []uint64{10, 40}, []uint64{10, 40}, 30, 0},
+		{"balance too low", 10, 0, "", vickreyResult, []uint64{5, 0}, []uint64{0, 10}, []uint64{0, 10}, 15, 0},
+		{"all-pay charges", 10, 0, "", allPayResult, []uint64{50, 50}, []uint64{0, 0}, []uint64{30, 40}, 50, 0},
+		{"seller pays the fee", 40, 1000, feePayerSeller, vickreyResult, []uint64{0, 0}, []uint64{10, 40}, []uint64{10, 40}, 27, 3},
+		{"fee rounded down", 40, 250, feePayerSeller, vickreyResult, []uint64{0, 0}, []uint64{10, 40}, []uint64{10, 40}, 30, 0},
+		{"buyer pays the fee", 40, 1000, feePayerBuyer, vickreyResult, []uint64{0, 0}, []uint64{7, 40}, []uint64{7, 40}, 30, 3},
+		{"buyer pays the price before the fee", 10, 1000, feePayerBuyer, vickreyResult, []uint64{21, 0}, []uint64{0, 10}, []uint64{0, 10}, 30, 1},
+		{"all-pay bidders pay the fee", 10, 1000, feePayerBuyer, allPayResult, []uint64{50, 50}, []uint64{0, 0}, []uint64{27, 38}, 50, 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			auction := &Auction{
+				Name:        "auction1",
+				Seller:      seller.cert.Raw,
+				Status:      AuctionStatus(Ended),
+				MinDeposit:  test.minDeposit,
+				Winner:      test.result.Winner,
+				HammerPrice: test.result.HammerPrice,
+				Result:      test.result,
+			}
+			if test.feeBps != 0 {
+				auction.FeeBps = test.feeBps
+				auction.FeePayer = test.feePayer
+				auction.FeeRecipient = platform.cert.Raw
+			}
+
+			// Every bidder has a bid and, if the auction requires it, a locked deposit
+			network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				for i, bidder := range bidders {
+					fingerprint := certFingerprint(bidder.cert.Raw)
+					err := putBid(ctx, auction.Name, &Bid{ID: fingerprint, Buyer: bidder.cert.Raw, BidPrice: 1})
+					if err != nil {
+						return err
+					}
+					err = putBalance(ctx, fingerprint, test.balances[i])
+					if err != nil {
+						return err
+					}
+					if test.minDeposit == 0 {
+						continue
+					}
+					key, err := depositKey(ctx, auction.Name, fingerprint)
+					if err != nil {
+						return err
+					}
+					depositJSON, err := json.Marshal(test.minDeposit)
+					if err != nil {
+						return err
+					}
+					err = ctx.GetStub().PutState(key, depositJSON)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			var refunds []Charge
+			var fee uint64
+			network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				refunds, fee, err = settleDeposits(ctx, auction)
+				return err
+			})
+
+			for i, bidder := range bidders {
+				var refund uint64
+				for _, charge := range refunds {
+					if reflect.DeepEqual(charge.Bidder, bidder.cert.Raw) {
+						refund = charge.Amount
+					}
+				}
+				if refund != test.wantRefunds[i] {
+					t.Fatalf("bidder %d got a refund of %d, want %d", i, refund, test.wantRefunds[i])
+				}
+				if balance := network.balance(bidder); balance != test.wantBalances[i] {
+					t.Fatalf("bidder %d has a balance of %d, want %d", i, balance, test.wantBalances[i])
+				}
+			}
+			if balance := network.balance(seller); balance != test.wantSeller {
+				t.Fatalf("the seller has a balance of %d, want %d", balance, test.wantSeller)
+			}
+			if balance := network.balance(platform); fee != test.wantFee || balance != test.wantFee {
+				t.Fatalf("the fee recipient got a fee of %d and has a balance of %d, want %d", fee, balance, test.wantFee)
+			}
+			if len(network.ledger.rangeKeys("\x00deposit\x00", "\x00deposit\x00\U0010FFFF")) != 0 {
+				t.Fatal("deposits were not removed")
+			}
+		})
+	}
+}
+
+func TestBidKeys(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	policy := []byte("policy of auction1")
+	network.ledger.policies[auctionKey("auction1")] = policy
+
+	commit1 := []byte("commit1")
+	commit2 := []byte("commit2")
+	network.mustSubmit(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		for _, bid := range []*Bid{{ID: "tx1", Buyer: bidder.cert.Raw, HiddenCommit: commit1}, {ID: "tx2", Buyer: bidder.cert.Raw, HiddenCommit: commit2}} {
+			err := putBid(ctx, "auction1", bid)
+			if err != nil {
+				return err
+			}
+			err = putBidCommitIndex(ctx, "auction1", bid)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// Another auction's bids are not in the range of auction1
+	network.mustSubmit(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return putBid(ctx, "auction10", &Bid{ID: "tx3", Buyer: bidder.cert.Raw})
+	})
+
+	for _, key := range network.bidKeys("auction1") {
+		if !reflect.DeepEqual(network.ledger.policies[key], policy) {
+			t.Fatalf("bid key %q does not have the policy of the auction", key)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		commit  []byte
+		wantID  string
+		prepare func(ctx contractapi.TransactionContextInterface) error
+	}{
+		{"indexed", commit1, "tx1", nil},
+		{"not indexed", []byte("commit3"), "", nil},
+		{"withdrawn", commit1, "", func(ctx contractapi.TransactionContextInterface) error {
+			return deleteBid(ctx, "auction1", "tx1")
+		}},
+		{"replaced", commit2, "", func(ctx contractapi.TransactionContextInterface) error {
+			return putBid(ctx, "auction1", &Bid{ID: "tx2", Buyer: bidder.cert.Raw, HiddenCommit: []byte("other")})
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.prepare != nil {
+				network.mustSubmit(bidder, test.prepare)
+			}
+			_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+				bid, err := findBidByCommit(ctx, "auction1", test.commit)
+				if err != nil {
+					return err
+				}
+				if (bid == nil && test.wantID != "") || (bid != nil && bid.ID != test.wantID) {
+					t.Fatalf("found bid %v, want %q", bid, test.wantID)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		bids, err := getBids(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		numBids, err := countBids(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		if len(bids) != 1 || numBids != 1 || bids[0].ID != "tx2" {
+			t.Fatalf("got %d bids and a count of %d, want only tx2", len(bids), numBids)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuctionStatusJSON(t *testing.T) {
+	for _, status := range []AuctionStatus{Open, Closed, Ended, Cancelled} {
+		statusJSON, err := json.Marshal(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(statusJSON) != `"`+status.String()+`"` {
+			t.Fatalf("status %d is encoded as %s", int(status), statusJSON)
+		}
+		var decoded AuctionStatus
+		err = json.Unmarshal(statusJSON, &decoded)
+		if err != nil || decoded != status {
+			t.Fatalf("status %s decoded as %v: %v", statusJSON, decoded, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		statusJSON string
+		want       AuctionStatus
+		wantErr    bool
+	}{
+		{"name", `"closed"`, Closed, false},
+		// Auctions stored before the string encoding have integer statuses
+		{"legacy integer", `2`, Ended, false},
+		{"unknown name", `"paused"`, Open, true},
+		{"unknown integer", `7`, Open, true},
+		{"wrong type", `true`, Open, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var status AuctionStatus
+			err := json.Unmarshal([]byte(test.statusJSON), &status)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error for %s: %v", test.statusJSON, err)
+			}
+			if status != test.want {
+				t.Fatalf("expected %v, got %v", test.want, status)
+			}
+		})
+	}
+
+	_, err := json.Marshal(AuctionStatus(7))
+	if err == nil {
+		t.Fatal("an unknown status was encoded")
+	}
+
+	// An auction stored by an earlier version of the chaincode can still be read
+	var auction Auction
+	err = json.Unmarshal([]byte(`{"name":"legacy","status":1}`), &auction)
+	if err != nil || auction.Status != Closed {
+		t.Fatalf("could not read the legacy auction: %v, status %v", err, auction.Status)
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want int
+	}{
+		{[]byte{}, 0},
+		{[]byte{0x80}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x00}, 16},
+		{[]byte{0x00, 0x10, 0x00}, 11},
+	}
+	for _, test := range tests {
+		got := leadingZeroBits(test.data)
+		if got != test.want {
+			t.Fatalf("leadingZeroBits(%x) = %d, want %d", test.data, got, test.want)
+		}
+	}
+}
+
+func TestUint64ToBigEndian(t *testing.T) {
+	got := uint64ToBigEndian(0x0102030405060708)
+	want := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("uint64ToBigEndian(0x0102030405060708) = %x, want %x", got, want)
+	}
+}
+
+func TestHashBidLayout(t *testing.T) {
+	cert := newTestCerts(t, 1)[0]
+	salt := bytes.Repeat([]byte{0xab}, minSaltLength)
+
+	// The preimage is the DER certificate, the price as 8 big endian bytes and the salt, without separators
+	preimage := append([]byte{}, cert.Raw...)
+	preimage = append(preimage, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x2c)
+	preimage = append(preimage, salt...)
+	want := make([]byte, bidCommitmentLength)
+	shake := sha3.NewShake256()
+	_, err := shake.Write(preimage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = shake.Read(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := hashBid(cert, 300, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("hashBid = %x, want %x", got, want)
+	}
+}
+
+func TestAuctionInvariantViolations(t *testing.T) {
+	certs := newTestCerts(t, 3)
+	hiddenCommit := make([]byte, bidCommitmentLength)
+
+	// newState returns a healthy auction which bidder 1 won against bidder 0, or its open state before the end
+	newState := func(ended bool) (*Auction, []Bid) {
+		auction := &Auction{Status: AuctionStatus(Open)}
+		bids := []Bid{
+			{ID: "b0", Buyer: certs[0].Raw, HiddenCommit: hiddenCommit},
+			{ID: "b1", Buyer: certs[1].Raw, HiddenCommit: hiddenCommit},
+		}
+		if ended {
+			auction.Status = AuctionStatus(Ended)
+			auction.Winner = certs[1].Raw
+			auction.HammerPrice = 30
+			auction.Result = &AuctionResult{Winner: certs[1].Raw, Winners: [][]byte{certs[1].Raw}, HammerPrice: 30}
+			bids[0].BidPrice = 30
+			bids[1].BidPrice = 40
+		}
+		return auction, bids
+	}
+	tests := []struct {
+		name           string
+		ended          bool
+		corrupt        func(auction *Auction, bids []Bid)
+		wantViolations []string
+	}{
+		{"healthy open auction", false, func(auction *Auction, bids []Bid) {}, []string{}},
+		{"healthy ended auction", true, func(auction *Auction, bids []Bid) {}, []string{}},
+		{"result before the end", false, func(auction *Auction, bids []Bid) {
+			auction.Winner = certs[1].Raw
+			auction.Result = &AuctionResult{Winner: certs[1].Raw}
+		}, []string{"auction is open but has a result"}},
+		{"hammer price without winner", true, func(auction *Auction, bids []Bid) {
+			auction.Winner = nil
+			auction.Result = nil
+		}, []string{"auction has a hammer price but no winner"}},
+		{"stored result differs", true, func(auction *Auction, bids []Bid) {
+			auction.Result.HammerPrice = 20
+		}, []string{"stored result does not match the winner and hammer price"}},
+		{"reserve above direct buy price", false, func(auction *Auction, bids []Bid) {
+			auction.HasReserve = true
+			auction.ReservePrice = 200
+			auction.DirectBuyPrice = 100
+		}, []string{"reserve price is above the direct buy price"}},
+		{"negative earliest close", false, func(auction *Auction, bids []Bid) {
+			auction.EarliestClose = -1
+		}, []string{"earliest close time is negative"}},
+		{"short hidden commit", false, func(auction *Auction, bids []Bid) {
+			bids[0].HiddenCommit = hiddenCommit[:10]
+		}, []string{"bid b0 has a hidden commit of 10 bytes"}},
+		{"bid without buyer", false, func(auction *Auction, bids []Bid) {
+			bids[1].Buyer = nil
+		}, []string{"bid b1 has no buyer"}},
+		{"revealed while open", false, func(auction *Auction, bids []Bid) {
+			bids[0].BidPrice = 30
+		}, []string{"bid b0 is revealed while the auction is open"}},
+		{"unrevealed after the end", true, func(auction *Auction, bids []Bid) {
+			bids[0].BidPrice = 0
+		}, []string{"bid b0 is not revealed although the auction ended"}},
+		{"winner without bid", true, func(auction *Auction, bids []Bid) {
+			auction.Winner = certs[2].Raw
+			auction.Result.Winner = certs[2].Raw
+			auction.Result.Winners = [][]byte{certs[2].Raw}
+		}, []string{"winner is not among the bidders"}},
+		{"hammer price above the winning bid", true, func(auction *Auction, bids []Bid) {
+			auction.HammerPrice = 50
+			auction.Result.HammerPrice = 50
+		}, []string{"hammer price is above the winning bid"}},
+		{"more winners than units", true, func(auction *Auction, bids []Bid) {
+			auction.Result.Winners = [][]byte{certs[1].Raw, certs[0].Raw}
+		}, []string{"auction has more winners than units"}},
+		{"several violations", true, func(auction *Auction, bids []Bid) {
+			auction.EarliestClose = -1
+			bids[1].Buyer = nil
+		}, []string{"earliest close time is negative", "bid b1 has no buyer", "winner is not among the bidders"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auction, bids := newState(test.ended)
+			test.corrupt(auction, bids)
+			violations := auctionInvariantViolations(auction, bids)
+			if !reflect.DeepEqual(violations, test.wantViolations) {
+				t.Fatalf("got violations %q, want %q", violations, test.wantViolations)
+			}
+		})
+	}
+}
+
+func TestCertPemToDer(t *testing.T) {
+	cert := newTestCerts(t, 1)[0]
+	canonical := *certDerToPem(cert.Raw)
+
+	// The same certificate with the base64 wrapped at 76 characters
+	encoded := base64.StdEncoding.EncodeToString(cert.Raw)
+	rewrapped := "-----BEGIN CERTIFICATE-----\n"
+	for len(encoded) > 76 {
+		rewrapped += encoded[:76] + "\n"
+		encoded = encoded[76:]
+	}
+	rewrapped += encoded + "\n-----END CERTIFICATE-----\n"
+
+	tests := []struct {
+		name    string
+		pemCert string
+		wantDer []byte
+	}{
+		{"canonical", canonical, cert.Raw},
+		{"CRLF line endings", strings.ReplaceAll(canonical, "\n", "\r\n"), cert.Raw},
+		{"surrounding text", "subject: bidder\n\n" + canonical + "\n\n", cert.Raw},
+		{"other line wrapping", rewrapped, cert.Raw},
+		{"other block type", strings.ReplaceAll(canonical, "CERTIFICATE", "PUBLIC KEY"), nil},
+		{"trailing data in the DER", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: append(append([]byte{}, cert.Raw...), 0)})), nil},
+		{"no certificate", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})), nil},
+		{"no PEM", "not a certificate", nil},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := certPemToDer(test.pemCert); !bytes.Equal(got, test.wantDer) {
+				t.Fatalf("certPemToDer returned %d bytes, want %d", len(got), len(test.wantDer))
+			}
+		})
+	}
+}
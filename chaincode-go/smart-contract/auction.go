@@ -5,12 +5,13 @@ SPDX-License-Identifier: Apache-2.0
 package auction
 
 import (
-	"crypto/rand"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"math/big"
 	"reflect"
 	"sort"
+	"unicode/utf8"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
@@ -23,7 +24,17 @@ type VickreyAuctionContract struct {
 /**************** AUCTION SELLER METHODS ****************/
 
 // CreateAuction creates a new auction
-func (s *VickreyAuctionContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionName string, directBuyPrice uint64) error {
+// The options are passed as a JSON object, e.g. {"hasReserve": true, "reservePrice": 50}, an empty string means no options.
+// They are taken as a string, because the contract metadata cannot describe a struct whose fields are all optional.
+func (s *VickreyAuctionContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionName string, directBuyPrice uint64, optionsJSON string) error {
+
+	var options AuctionOptions
+	if optionsJSON != "" {
+		errOptions := json.Unmarshal([]byte(optionsJSON), &options)
+		if errOptions != nil {
+			return newAuctionError(ErrCodeInvalidArgument, "could not decode the options: %v", errOptions)
+		}
+	}
 
 	// get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
@@ -31,39 +42,209 @@ func (s *VickreyAuctionContract) CreateAuction(ctx contractapi.TransactionContex
 		return fmt.Errorf("failed to get client identity: %v", errClientID)
 	}
 
+	// The name is part of the world state keys of the auction and its bids
+	maxNameLength, errMaxNameLength := getMaxAuctionNameLength(ctx)
+	if errMaxNameLength != nil {
+		return fmt.Errorf("could not get the maximum auction name length: %v", errMaxNameLength)
+	}
+	errName := validateAuctionName(auctionName, maxNameLength)
+	if errName != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "%v", errName)
+	}
+
+	// The item information is copied into every summary, so its size is limited
+	if len(options.Description) > maxDescriptionLength || !utf8.ValidString(options.Description) {
+		return newAuctionError(ErrCodeInvalidArgument, "description must be valid UTF-8 of at most %d bytes", maxDescriptionLength)
+	}
+	if len(options.MetadataURI) > maxMetadataURILength {
+		return newAuctionError(ErrCodeInvalidArgument, "metadata URI must be at most %d bytes long", maxMetadataURILength)
+	}
+
+	// Off-chain systems interpret the prices in this unit
+	errCurrency := checkCurrency(options.Currency)
+	if errCurrency != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "%v", errCurrency)
+	}
+
+	// The salt minimum can only be raised, a shorter salt would make the bids easier to brute-force
+	if options.MinSaltBytes != 0 && options.MinSaltBytes < minSaltLength {
+		return newAuctionError(ErrCodeInvalidArgument, "minimum salt length must be at least %d bytes", minSaltLength)
+	}
+
+	// Fees are only settled on the ledger, so they need deposits
+	if options.FeeBps > maxFeeBps {
+		return newAuctionError(ErrCodeInvalidArgument, "fee must be at most %d basis points", maxFeeBps)
+	}
+	if options.FeePayer != "" && options.FeePayer != feePayerSeller && options.FeePayer != feePayerBuyer {
+		return newAuctionError(ErrCodeInvalidArgument, "fee payer must be %q or %q", feePayerSeller, feePayerBuyer)
+	}
+	if options.FeeBps != 0 && options.MinDeposit == 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "fees require a minimum deposit")
+	}
+
+	auctionType, errAuctionType := parseAuctionType(options.AuctionType)
+	if errAuctionType != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "%v", errAuctionType)
+	}
+
+	// The winners of a multi-unit auction pay a uniform price in Vickrey auctions and their own bids in first-price auctions
+	if options.Quantity > 1 && auctionType != AuctionType(Vickrey) && auctionType != AuctionType(FirstPrice) {
+		return newAuctionError(ErrCodeInvalidArgument, "only Vickrey and first-price auctions can sell more than one unit")
+	}
+
+	// A direct buy would take all units at once, so it is only available for single-unit auctions
+	if options.Quantity > 1 && directBuyPrice != 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "direct buy is not supported for auctions with more than one unit")
+	}
+
+	// Nobody could bid more than the direct buy price, which wins anyway
+	if directBuyPrice != 0 && options.MinBidPrice > directBuyPrice {
+		return newAuctionError(ErrCodeInvalidArgument, "minimum bid price cannot be higher than the direct buy price")
+	}
+
+	// The range of valid bids must not be empty, and a reserve above it could never be met
+	if options.MaxBidPrice != 0 {
+		if options.MinBidPrice > options.MaxBidPrice {
+			return newAuctionError(ErrCodeInvalidArgument, "minimum bid price cannot be higher than the maximum bid price")
+		}
+		if options.HasReserve && options.ReservePrice > options.MaxBidPrice {
+			return newAuctionError(ErrCodeInvalidArgument, "reserve price cannot be higher than the maximum bid price")
+		}
+	}
+
+	// Only Dutch auctions have a price clock, and it must run down
+	if auctionType == AuctionType(Dutch) {
+		if options.StartPrice == 0 || options.PriceDecrement == 0 || options.DecrementInterval <= 0 {
+			return newAuctionError(ErrCodeInvalidArgument, "Dutch auctions need a start price, a price decrement and a decrement interval")
+		}
+		if directBuyPrice != 0 || options.Quantity > 1 || options.MinDeposit != 0 {
+			return newAuctionError(ErrCodeInvalidArgument, "Dutch auctions support neither direct buy, more than one unit nor deposits")
+		}
+		if options.HasReserve && options.ReservePrice > options.StartPrice {
+			return newAuctionError(ErrCodeInvalidArgument, "reserve price cannot be higher than the start price")
+		}
+	} else if options.StartPrice != 0 || options.PriceDecrement != 0 || options.DecrementInterval != 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "only Dutch auctions have a price clock")
+	}
+	if options.BidIncrement != 0 && auctionType != AuctionType(English) {
+		return newAuctionError(ErrCodeInvalidArgument, "only English auctions have a bid increment")
+	}
+
+	// Open bids carry no hidden commit which could be signed
+	if options.Unsealed && auctionType == AuctionType(Dutch) {
+		return newAuctionError(ErrCodeInvalidArgument, "Dutch auctions do not take bids")
+	}
+	sealedBids := !options.Unsealed && auctionType != AuctionType(Dutch) && auctionType != AuctionType(English)
+	if options.RequireBidSignature && !sealedBids {
+		return newAuctionError(ErrCodeInvalidArgument, "bid signatures can only be required for sealed bids")
+	}
+	if options.CommitDifficulty != 0 && !sealedBids {
+		return newAuctionError(ErrCodeInvalidArgument, "a commit difficulty can only be set for sealed bids")
+	}
+	if options.CommitDifficulty > maxCommitDifficulty {
+		return newAuctionError(ErrCodeInvalidArgument, "the commit difficulty must be at most %d bits", maxCommitDifficulty)
+	}
+
+	// The clock of a Dutch auction starts with the auction
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+
 	// check if such an auction already exists
 	auctionExists, errAuctionExist := doesAuctionExist(ctx, auctionName)
 	if errAuctionExist != nil {
 		return fmt.Errorf("failed to check if an auction with the same name already exists: %v", errAuctionExist)
 	}
 	if auctionExists {
-		return fmt.Errorf("auction with the same name already exists")
+		return newAuctionError(ErrCodeAlreadyExists, "auction with the same name already exists")
+	}
+
+	// The same item must not be sold in two unfinished auctions at the same time
+	if options.ItemRef != "" {
+		itemAuctions, errItemAuctions := getAuctionsByItemRef(ctx, options.ItemRef)
+		if errItemAuctions != nil {
+			return fmt.Errorf("failed to look up auctions with the same item reference: %v", errItemAuctions)
+		}
+		if len(itemAuctions) != 0 {
+			return newAuctionError(ErrCodeAlreadyExists, "the item is already sold in auction %q", itemAuctions[0])
+		}
+	}
+
+	// The fee recipient is fixed when the auction is created, so that the admin cannot redirect the fee of a running auction
+	var feeRecipient []byte = nil
+	if options.FeeBps != 0 {
+		var errFeeRecipient error
+		feeRecipient, errFeeRecipient = getFeeRecipient(ctx)
+		if errFeeRecipient != nil {
+			return fmt.Errorf("could not get the fee recipient: %v", errFeeRecipient)
+		}
+		if feeRecipient == nil {
+			return newAuctionError(ErrCodeInvalidStatus, "the admin has not configured a fee recipient")
+		}
+		if reflect.DeepEqual(feeRecipient, clientID.Raw) {
+			return newAuctionError(ErrCodePermissionDenied, "the fee recipient cannot charge fees on their own auction")
+		}
+	}
+	feePayer := options.FeePayer
+	if options.FeeBps != 0 && feePayer == "" {
+		feePayer = feePayerSeller
 	}
 
 	// create new auction and save it
 	auction := Auction{
-		Name:           auctionName,
-		Seller:         clientID.Raw,
-		Status:         AuctionStatus(Open),
-		DirectBuyPrice: directBuyPrice,
-		Bids:           []Bid{},
-		Winner:         nil,
-		HammerPrice:    0,
+		Name:                 auctionName,
+		Seller:               clientID.Raw,
+		Status:               AuctionStatus(Open),
+		Type:                 auctionType,
+		DirectBuyPrice:       directBuyPrice,
+		ItemRef:              options.ItemRef,
+		HasReserve:           options.HasReserve,
+		ReservePrice:         options.ReservePrice,
+		AllowedMSPs:          options.AllowedMSPs,
+		PriceScale:           options.PriceScale,
+		EarliestClose:        options.EarliestClose,
+		ScrubLosersAfterEnd:  options.ScrubLosersAfterEnd,
+		MinSaltBytes:         options.MinSaltBytes,
+		Quantity:             options.Quantity,
+		MinDeposit:           options.MinDeposit,
+		FeeBps:               options.FeeBps,
+		FeePayer:             feePayer,
+		FeeRecipient:         feeRecipient,
+		MaxBids:              options.MaxBids,
+		AnonymousIDs:         options.AnonymousIDs,
+		MinBidPrice:          options.MinBidPrice,
+		MaxBidPrice:          options.MaxBidPrice,
+		StartPrice:           options.StartPrice,
+		PriceDecrement:       options.PriceDecrement,
+		DecrementInterval:    options.DecrementInterval,
+		ClockStart:           timestamp,
+		BidIncrement:         options.BidIncrement,
+		Description:          options.Description,
+		RequireBidSignature:  options.RequireBidSignature,
+		SoleBidderPaysOwnBid: options.SoleBidderPaysOwnBid,
+		Unsealed:             options.Unsealed,
+		RedactRevealedPrices: options.RedactRevealedPrices,
+		Currency:             options.Currency,
+		CommitDifficulty:     options.CommitDifficulty,
+		HideBidCount:         options.HideBidCount,
+		MetadataURI:          options.MetadataURI,
+		Winner:               nil,
+		HammerPrice:          0,
+		Result:               nil,
 	}
 	errPutAuction := putAuction(ctx, &auction)
 	if errPutAuction != nil {
 		return fmt.Errorf("could not save the new auction in the world state: %v", errPutAuction)
 	}
+	errItemRefIndex := addItemRefIndex(ctx, &auction)
+	if errItemRefIndex != nil {
+		return fmt.Errorf("could not add the auction to the item reference index: %v", errItemRefIndex)
+	}
 
 	// Inform the users about the auction creation
 	auctionSummaryErr :=
-		setAuctionSummaryEvent(ctx, &AuctionSummary{
-			Name:           auction.Name,
-			Seller:         auction.Seller,
-			Status:         auction.Status,
-			DirectBuyPrice: auction.DirectBuyPrice,
-			Result:         nil,
-		})
+		setAuctionSummaryEvent(ctx, newAuctionSummary(&auction, []Bid{}))
 	if auctionSummaryErr != nil {
 		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
 	}
@@ -72,7 +253,8 @@ func (s *VickreyAuctionContract) CreateAuction(ctx contractapi.TransactionContex
 }
 
 // UpdateAuctionStatus updates the auction status (this can only be done by the auction seller)
-func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+// A non-zero expectedVersion must match the version of the open auction, see checkExpectedVersion.
+func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionName string, expectedVersion uint64) error {
 
 	// Get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
@@ -86,12 +268,12 @@ func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContext
 		return fmt.Errorf("could not get the auction: %v", errGetAuction)
 	}
 	if auction == nil {
-		return fmt.Errorf("auction not found")
+		return newAuctionError(ErrCodeNotFound, "auction not found")
 	}
 
 	// Check if the submitting client is the seller of the auction
 	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
-		return fmt.Errorf("only the auction seller can update the auction status")
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can update the auction status")
 	}
 
 	// If auction is already closed, do nothing
@@ -99,6 +281,28 @@ func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContext
 		return nil
 	}
 
+	errVersion := checkExpectedVersion(auction, expectedVersion)
+	if errVersion != nil {
+		return errVersion
+	}
+
+	// Keep the seller's commitment to the earliest close time
+	if auction.EarliestClose != 0 {
+		timestamp, errTimestamp := getTxTimestamp(ctx)
+		if errTimestamp != nil {
+			return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+		}
+		if timestamp < auction.EarliestClose {
+			return newAuctionError(ErrCodeInvalidStatus, "auction cannot be closed before %d", auction.EarliestClose)
+		}
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+
 	// Change auction status from open to closed
 	auction.Status = AuctionStatus(Closed)
 	errPutAuction := putAuction(ctx, auction)
@@ -108,13 +312,129 @@ func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContext
 
 	// Inform the users about the auction status change
 	auctionSummaryErr :=
-		setAuctionSummaryEvent(ctx, &AuctionSummary{
-			Name:           auction.Name,
-			Seller:         auction.Seller,
-			Status:         auction.Status,
-			DirectBuyPrice: auction.DirectBuyPrice,
-			Result:         nil,
-		})
+		setAuctionSummaryEvent(ctx, newAuctionSummary(auction, bids))
+	if auctionSummaryErr != nil {
+		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	}
+
+	return nil
+}
+
+// ReopenAuction reverts an accidental close, so that bids can be submitted again
+// This is only possible as long as no bid has been revealed.
+func (s *VickreyAuctionContract) ReopenAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can update the auction status")
+	}
+
+	// If auction is already open, do nothing
+	if auction.Status == AuctionStatus(Open) {
+		return nil
+	}
+	if auction.Status != AuctionStatus(Closed) {
+		return newAuctionError(ErrCodeInvalidStatus, "only closed auctions can be reopened")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Once a bid is revealed, other bidders could adapt their bids
+	for i := range bids {
+		if bids[i].BidPrice != 0 {
+			return newAuctionError(ErrCodeInvalidStatus, "cannot reopen the auction, because bids have already been revealed")
+		}
+	}
+
+	// Change auction status from closed to open
+	auction.Status = AuctionStatus(Open)
+	errPutAuction := putAuction(ctx, auction)
+	if errPutAuction != nil {
+		return fmt.Errorf("failed to save the updated auction")
+	}
+
+	// Inform the users about the auction status change
+	auctionSummaryErr :=
+		setAuctionSummaryEvent(ctx, newAuctionSummary(auction, bids))
+	if auctionSummaryErr != nil {
+		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	}
+
+	return nil
+}
+
+// CancelAuction cancels an auction which has no bids yet and removes it from the world state
+func (s *VickreyAuctionContract) CancelAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can cancel the auction")
+	}
+
+	// An auction can only be cancelled while it is open and nobody has bid yet
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "only open auctions can be cancelled")
+	}
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+	if len(bids) != 0 {
+		return newAuctionError(ErrCodeInvalidStatus, "cannot cancel an auction which already has bids")
+	}
+
+	// Remove the auction and the index entries of withdrawn bids from the world state
+	errCommitIndex := deleteBidCommitIndex(ctx, auctionName)
+	if errCommitIndex != nil {
+		return fmt.Errorf("could not delete the hidden commit index: %v", errCommitIndex)
+	}
+	errDeleteAuction := deleteAuction(ctx, auctionName)
+	if errDeleteAuction != nil {
+		return fmt.Errorf("could not delete the auction: %v", errDeleteAuction)
+	}
+	errItemRefIndex := removeItemRefIndex(ctx, auction)
+	if errItemRefIndex != nil {
+		return fmt.Errorf("could not remove the auction from the item reference index: %v", errItemRefIndex)
+	}
+
+	// Inform the users about the cancellation
+	auctionSummary := newAuctionSummary(auction, bids)
+	auctionSummary.Status = AuctionStatus(Cancelled)
+	auctionSummaryErr := setAuctionSummaryEvent(ctx, auctionSummary)
 	if auctionSummaryErr != nil {
 		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
 	}
@@ -123,7 +443,8 @@ func (s *VickreyAuctionContract) CloseAuction(ctx contractapi.TransactionContext
 }
 
 // EndAuction determines the highest bidder and the hammer price
-func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+// A non-zero expectedVersion must match the version of the auction, unless it has already ended, see checkExpectedVersion.
+func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionName string, expectedVersion uint64) error {
 	// Get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
 	if errClientID != nil {
@@ -136,12 +457,12 @@ func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextIn
 		return fmt.Errorf("could not get the auction: %v", errGetAuction)
 	}
 	if auction == nil {
-		return fmt.Errorf("auction not found")
+		return newAuctionError(ErrCodeNotFound, "auction not found")
 	}
 
 	// Check if the submitting client is the seller of the auction
 	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
-		return fmt.Errorf("only the auction seller can end the auction")
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can end the auction")
 	}
 
 	// If the auction has already ended, do nothing
@@ -149,111 +470,92 @@ func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextIn
 		return nil
 	}
 
-	// Build a mapping from the buyer (PEM certificate) to their highest bid
-	buyerToBid := make(map[string]uint64)
-	for i := range auction.Bids {
-		bid := &auction.Bids[i]
-		if bid.BidPrice == 0 {
-			return fmt.Errorf("cannot end auction, because not all bids are revealed yet")
-		}
-		buyerCertPem := certDerToPem(bid.Buyer)
-		if buyerCertPem == nil {
-			return fmt.Errorf("could not convert certificate from DER to PEM format")
-		}
-		prevBid, exists := buyerToBid[*buyerCertPem]
-		if !exists || bid.BidPrice > prevBid {
-			buyerToBid[*buyerCertPem] = bid.BidPrice
-		}
+	errVersion := checkExpectedVersion(auction, expectedVersion)
+	if errVersion != nil {
+		return errVersion
+	}
+
+	// The bidders must have had the chance to reveal their bids
+	if auction.Status != AuctionStatus(Closed) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction must be closed before it can be ended")
 	}
 
-	type BidPriceBuyerPair struct {
-		BidPrice uint64
-		Buyer    []byte
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
 	}
 
-	// Convert map to (BidPrice, Buyer) slice
-	bidPriceToBuyer := make([]BidPriceBuyerPair, 0, len(buyerToBid))
+	// Determine the winners and the hammer price from the revealed bids
+	outcome, errOutcome := computeAuctionOutcome(auction, bids)
+	if errOutcome != nil {
+		return errOutcome
+	}
 
-	for buyer, bidPrice := range buyerToBid {
-		buyerCertDer := certPemToDer(buyer)
-		if buyerCertDer == nil {
-			return fmt.Errorf("could not convert certificate from PEM to DER format")
-		}
-		bidPriceToBuyer = append(bidPriceToBuyer, BidPriceBuyerPair{
-			BidPrice: bidPrice,
-			Buyer:    buyerCertDer,
-		})
+	// If more bidders bid the lowest winning price than units are left, the winners are chosen pseudo-randomly.
+	// The choice is derived from the transaction ID, so that all endorsers choose the same winners.
+	winningCandidates, errTieBreak :=
+		tieBreakSelection([]byte(ctx.GetStub().GetTxID()), uint64(len(outcome.candidates)), outcome.numCandidateUnits)
+	if errTieBreak != nil {
+		return fmt.Errorf("could not choose the winners among the bidders with the same price: %v", errTieBreak)
+	}
+	chosenCandidates := make([][]byte, 0, len(winningCandidates))
+	for _, candidate := range winningCandidates {
+		chosenCandidates = append(chosenCandidates, outcome.candidates[candidate])
 	}
 
-	// Sort bidders by descending bid price
-	sort.Slice(bidPriceToBuyer, func(i int, j int) bool {
-		return bidPriceToBuyer[i].BidPrice > bidPriceToBuyer[j].BidPrice
-	})
+	// Update auction state
+	auction.Result = outcome.result(chosenCandidates)
+	auction.HammerPrice = auction.Result.HammerPrice
+	auction.Winner = auction.Result.Winner
+	auction.Status = AuctionStatus(Ended)
 
-	var auctionSummary *AuctionSummary = nil
-	if len(bidPriceToBuyer) == 0 {
-		// No bids submitted => no winner
-		// Update auction state
-		auction.HammerPrice = 0
-		auction.Winner = nil
-		auction.Status = AuctionStatus(Ended)
-
-		// Set auction summary
-		auctionSummary = &AuctionSummary{
-			Name:           auction.Name,
-			Seller:         auction.Seller,
-			Status:         auction.Status,
-			DirectBuyPrice: auction.DirectBuyPrice,
-			Result: &AuctionResult{
-				Winner:      nil,
-				HammerPrice: 0,
-				DirectBuy:   false,
-			},
-		}
-	} else {
-		// Determine hammer price
-		highestPrice := bidPriceToBuyer[0].BidPrice
-		hammerPrice := highestPrice
-		if len(bidPriceToBuyer) > 1 {
-			hammerPrice = bidPriceToBuyer[1].BidPrice
-		}
-
-		// If there are multiple bidders with the same highest bid, one is chosen at random
-		// Potential problem: if there are multiple endorsers, their outcomes might not match
-		numberOfCandidates := uint(0)
-		for i := range bidPriceToBuyer {
-			if bidPriceToBuyer[i].BidPrice < highestPrice {
-				break
+	// Record who finalized the auction and which orgs had to endorse the result
+	mspID, errMSPID := ctx.GetClientIdentity().GetMSPID()
+	if errMSPID != nil {
+		return fmt.Errorf("could not get the client MSP ID: %v", errMSPID)
+	}
+	endorsementPolicy, errEndorsementPolicy := getAuctionEndorsementPolicy(ctx, auctionName)
+	if errEndorsementPolicy != nil {
+		return fmt.Errorf("could not get the auction endorsement policy: %v", errEndorsementPolicy)
+	}
+	auction.FinalizedBy = mspID
+	auction.EndorsingOrgs = endorsementPolicy.Orgs
+	auction.EndorsementQuorum = endorsementPolicy.Quorum
+
+	// The bidders pay what they owe from their deposits, the rest is refunded
+	refunds, fee, errSettleDeposits := settleDeposits(ctx, auction)
+	if errSettleDeposits != nil {
+		return fmt.Errorf("could not settle the deposits: %v", errSettleDeposits)
+	}
+	auction.Result.Refunds = refunds
+	auction.Result.Fee = fee
+
+	// Set auction summary
+	// The event carries the bid book, so that anyone can verify the result.
+	auctionSummary := newAuctionSummary(auction, bids)
+	auctionSummary.BidBook = newBidBook(auction, bids)
+
+	// Only keep the prices of the losing bids, if the seller asked for it
+	// The summary above was built before, so it still counts all bidders and lists all of them in the bid book.
+	if auction.ScrubLosersAfterEnd {
+		for i := range bids {
+			bid := &bids[i]
+			if isAuctionWinner(auction, bid.Buyer) {
+				continue
 			}
-			numberOfCandidates += 1
-		}
-		numberOfCandidatesBigInt := new(big.Int).SetUint64(uint64(numberOfCandidates))
-		winningCandidate, errRand := rand.Int(rand.Reader, numberOfCandidatesBigInt)
-		if errRand != nil {
-			return fmt.Errorf("could not get a random number: %v", errRand)
-		}
-
-		if !winningCandidate.IsUint64() {
-			return fmt.Errorf("winning candidate index cannot be represented as a uint64")
-		}
-		winner := bidPriceToBuyer[winningCandidate.Uint64()].Buyer
-
-		// Update auction state
-		auction.HammerPrice = hammerPrice
-		auction.Winner = winner
-		auction.Status = AuctionStatus(Ended)
-
-		// Set auction summary
-		auctionSummary = &AuctionSummary{
-			Name:           auction.Name,
-			Seller:         auction.Seller,
-			Status:         auction.Status,
-			DirectBuyPrice: auction.DirectBuyPrice,
-			Result: &AuctionResult{
-				Winner:      auction.Winner,
-				HammerPrice: auction.HammerPrice,
-				DirectBuy:   false,
-			},
+			bid.Buyer = nil
+			bid.HiddenCommit = nil
+			bid.Signature = nil
+			errPutBid := putBid(ctx, auctionName, bid)
+			if errPutBid != nil {
+				return fmt.Errorf("could not scrub losing bid: %v", errPutBid)
+			}
+		}
+		// The index keys contain the hidden commits, and no more bids can be submitted anyway
+		errCommitIndex := deleteBidCommitIndex(ctx, auctionName)
+		if errCommitIndex != nil {
+			return fmt.Errorf("could not delete the hidden commit index: %v", errCommitIndex)
 		}
 	}
 
@@ -262,6 +564,13 @@ func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextIn
 	if errPutAuction != nil {
 		return fmt.Errorf("could not save ended auction: %v", errPutAuction)
 	}
+	// The summary was built before the auction was saved
+	auctionSummary.Version = auction.Version
+	auctionSummary.LastUpdated = auction.LastUpdated
+	errItemRefIndex := removeItemRefIndex(ctx, auction)
+	if errItemRefIndex != nil {
+		return fmt.Errorf("could not remove the auction from the item reference index: %v", errItemRefIndex)
+	}
 
 	// Set auction summary event
 	auctionSummaryErr := setAuctionSummaryEvent(ctx, auctionSummary)
@@ -272,22 +581,11 @@ func (s *VickreyAuctionContract) EndAuction(ctx contractapi.TransactionContextIn
 	return nil
 }
 
-/**************** AUCTION BIDDER METHODS ****************/
-
-// Bid is called by a bidder to submit a hidden bid
-// Apparently, it is not possible to pass a byte array to the contract,
-// therefore the client has to send the hidden commit hex encoded.
-func (s *VickreyAuctionContract) Bid(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommitHex string) error {
-	// Decode hidden commit
-	hiddenCommit, errDecode := hex.DecodeString(hiddenCommitHex)
-	if errDecode != nil {
-		return fmt.Errorf("could not decode hidden commit: %v", errDecode)
-	}
-
-	// The hiddenCommit should be a 512 bit long hash
-	if len(hiddenCommit) != 64 {
-		return fmt.Errorf("hiddenCommit is not 512 bit long")
-	}
+// SetAuctionEndorsementPolicy requires a quorum of the given orgs to endorse any further change of the auction
+// The policy is set as a state-based endorsement policy on the auction key, so it applies to bids as well as to EndAuction.
+// The orgs are given as a JSON array of MSP IDs, e.g. ["Org1MSP","Org2MSP","Org3MSP"], and the quorum is the number of them
+// whose peers must endorse, e.g. 2. A quorum of 0 requires all of them. An empty list removes the policy.
+func (s *VickreyAuctionContract) SetAuctionEndorsementPolicy(ctx contractapi.TransactionContextInterface, auctionName string, orgs []string, quorum uint32) error {
 
 	// Get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
@@ -301,49 +599,78 @@ func (s *VickreyAuctionContract) Bid(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("could not get the auction: %v", errGetAuction)
 	}
 	if auction == nil {
-		return fmt.Errorf("auction not found")
+		return newAuctionError(ErrCodeNotFound, "auction not found")
 	}
 
-	// Can only submit new bid while auction is open
-	if auction.Status != AuctionStatus(Open) {
-		return fmt.Errorf("auction is closed")
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can set the endorsement policy")
 	}
 
-	// Add bid to auction
-	auction.Bids = append(auction.Bids, Bid{
-		Buyer:        clientID.Raw,
-		BidPrice:     0,
-		HiddenCommit: hiddenCommit,
-	})
-
-	// Save updated auction
-	errPutAuction := putAuction(ctx, auction)
-	if errPutAuction != nil {
-		return fmt.Errorf("could not save the updated auction: %v", errPutAuction)
+	// The result of an ended auction cannot be changed anymore
+	if auction.Status.IsTerminal() {
+		return newAuctionError(ErrCodeInvalidStatus, "auction has already ended")
 	}
 
-	return nil
-}
-
-// OpenBid reveals the bid price of a bid
-func (s *VickreyAuctionContract) OpenBid(ctx contractapi.TransactionContextInterface, auctionName string, bidPrice uint64, saltHex string) error {
+	// Every org counts once, in a fixed order so that all endorsers build the same policy
+	uniqueOrgs := []string{}
+	seenOrgs := make(map[string]bool)
+	for _, org := range orgs {
+		if org == "" {
+			return newAuctionError(ErrCodeInvalidArgument, "MSP IDs cannot be empty")
+		}
+		if !seenOrgs[org] {
+			seenOrgs[org] = true
+			uniqueOrgs = append(uniqueOrgs, org)
+		}
+	}
+	sort.Strings(uniqueOrgs)
+	if int(quorum) > len(uniqueOrgs) {
+		return newAuctionError(ErrCodeInvalidArgument, "the quorum of %d is larger than the number of orgs", quorum)
+	}
+	if quorum == 0 {
+		quorum = uint32(len(uniqueOrgs))
+	}
 
-	// Check if the bidPrice is reasonable
-	if bidPrice == 0 {
-		return fmt.Errorf("bid price cannot be zero")
+	// Build the new policy
+	var policy []byte = nil
+	if len(uniqueOrgs) != 0 {
+		var errPolicy error
+		policy, errPolicy = newQuorumPolicy(uniqueOrgs, int(quorum))
+		if errPolicy != nil {
+			return fmt.Errorf("could not encode the endorsement policy: %v", errPolicy)
+		}
 	}
 
-	// Decode hidden commit
-	salt, errSaltDecode := hex.DecodeString(saltHex)
-	if errSaltDecode != nil {
-		return fmt.Errorf("could not decode salt: %v", errSaltDecode)
+	errSetPolicy := ctx.GetStub().SetStateValidationParameter(auctionKey(auctionName), policy)
+	if errSetPolicy != nil {
+		return fmt.Errorf("could not set the endorsement policy: %v", errSetPolicy)
 	}
 
-	// Check salt minimum requirements
-	if len(salt) < 64 {
-		return fmt.Errorf("salt should be at least 64 bytes long")
+	// The bids are stored under their own keys, which need the same policy
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+	for i := range bids {
+		key, errBidKey := bidKey(ctx, auctionName, bids[i].ID)
+		if errBidKey != nil {
+			return fmt.Errorf("could not get the bid key: %v", errBidKey)
+		}
+		errSetPolicy = ctx.GetStub().SetStateValidationParameter(key, policy)
+		if errSetPolicy != nil {
+			return fmt.Errorf("could not set the endorsement policy of a bid: %v", errSetPolicy)
+		}
 	}
 
+	return nil
+}
+
+// InviteBidder adds a bidder to the invited bidders of the auction
+// Once a bidder is invited, only invited bidders can bid or buy directly.
+// The bidder's certificate is given in PEM format.
+func (s *VickreyAuctionContract) InviteBidder(ctx contractapi.TransactionContextInterface, auctionName string, bidderPem string) error {
+
 	// Get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
 	if errClientID != nil {
@@ -356,30 +683,32 @@ func (s *VickreyAuctionContract) OpenBid(ctx contractapi.TransactionContextInter
 		return fmt.Errorf("could not get the auction: %v", errGetAuction)
 	}
 	if auction == nil {
-		return fmt.Errorf("auction not found")
+		return newAuctionError(ErrCodeNotFound, "auction not found")
 	}
 
-	clientCert, errCert := ctx.GetClientIdentity().GetX509Certificate()
-	if errCert != nil {
-		return fmt.Errorf("could not get client certificate")
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can invite bidders")
 	}
 
-	bidHash, errHashBid := hashBid(clientCert, bidPrice, salt)
-	if errHashBid != nil {
-		return errHashBid
+	// Invitations only make sense while bids can be submitted
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction is closed")
 	}
 
-	// Iterate over the bids and try to reveal any
-	for i := range auction.Bids {
-		bid := &auction.Bids[i]
-		if reflect.DeepEqual(bid.Buyer, clientID.Raw) && bid.BidPrice == 0 {
-			// Check if hidden commit matches the hash
-			if reflect.DeepEqual(bid.HiddenCommit, bidHash) {
-				// The bid price is revealed
-				bid.BidPrice = bidPrice
-			}
+	// Convert the certificate to the DER format, in which the bids store it
+	bidderCertDer := certPemToDer(bidderPem)
+	if bidderCertDer == nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	// Inviting a bidder twice does not change anything
+	for _, invitedBidder := range auction.InvitedBidders {
+		if reflect.DeepEqual(invitedBidder, bidderCertDer) {
+			return nil
 		}
 	}
+	auction.InvitedBidders = append(auction.InvitedBidders, bidderCertDer)
 
 	// Save the updated auction
 	errPutAuction := putAuction(ctx, auction)
@@ -390,8 +719,10 @@ func (s *VickreyAuctionContract) OpenBid(ctx contractapi.TransactionContextInter
 	return nil
 }
 
-// DirectBuy: The buyer should pay at least auction.DirectBuyPrice to directly purchase the auction item
-func (s *VickreyAuctionContract) DirectBuy(ctx contractapi.TransactionContextInterface, auctionName string, price uint64) error {
+// StrikeBidder removes the unrevealed bids of a bidder while the auction is open, e.g. in case of abuse
+// Revealed bids of open-bid auctions are kept. The bidder's certificate is given in PEM format.
+func (s *VickreyAuctionContract) StrikeBidder(ctx contractapi.TransactionContextInterface, auctionName string, bidderPem string) error {
+
 	// Get ID of submitting client
 	clientID, errClientID := getSubmittingClientIdentity(ctx)
 	if errClientID != nil {
@@ -404,47 +735,1900 @@ func (s *VickreyAuctionContract) DirectBuy(ctx contractapi.TransactionContextInt
 		return fmt.Errorf("could not get the auction: %v", errGetAuction)
 	}
 	if auction == nil {
-		return fmt.Errorf("auction not found")
+		return newAuctionError(ErrCodeNotFound, "auction not found")
 	}
 
-	// Check auction status
-	if auction.Status == AuctionStatus(Ended) {
-		return fmt.Errorf("auction has already ended")
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can strike bidders")
 	}
 
-	// Check direct buy validity
-	if auction.DirectBuyPrice == 0 {
-		return fmt.Errorf("direct buy is disabled for this auction")
+	// Once the auction is closed, the bidders rely on their bids being counted
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction is closed")
 	}
-	if price < auction.DirectBuyPrice {
-		return fmt.Errorf("payment amount not sufficient for a direct buy")
+
+	// Convert the certificate to the DER format, in which the bids store it
+	bidderCertDer := certPemToDer(bidderPem)
+	if bidderCertDer == nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
 	}
 
-	// End the auction
-	auction.HammerPrice = price
-	auction.Winner = clientID.Raw
-	auction.Status = AuctionStatus(Ended)
-	errPutAuction := putAuction(ctx, auction)
-	if errPutAuction != nil {
-		return fmt.Errorf("could not save ended auction: %v", errPutAuction)
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
 	}
 
-	// Inform the users about the auction result
-	auctionSummaryErr :=
-		setAuctionSummaryEvent(ctx, &AuctionSummary{
-			Name:           auction.Name,
-			Seller:         auction.Seller,
-			Status:         auction.Status,
-			DirectBuyPrice: auction.DirectBuyPrice,
-			Result: &AuctionResult{
-				Winner:      auction.Winner,
-				HammerPrice: auction.HammerPrice,
-				DirectBuy:   true,
-			},
-		})
-	if auctionSummaryErr != nil {
-		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	// Delete the unrevealed bids of the bidder
+	numStruckBids := 0
+	numKeptBids := 0
+	for i := range bids {
+		bid := &bids[i]
+		if !reflect.DeepEqual(bid.Buyer, bidderCertDer) {
+			continue
+		}
+		if bid.BidPrice != 0 {
+			numKeptBids += 1
+			continue
+		}
+		errDeleteBid := deleteBid(ctx, auctionName, bid.ID)
+		if errDeleteBid != nil {
+			return fmt.Errorf("could not delete the bid: %v", errDeleteBid)
+		}
+		numStruckBids += 1
+	}
+	if numStruckBids == 0 {
+		return newAuctionError(ErrCodeNotFound, "the bidder has no unrevealed bids")
+	}
+
+	// The deposit still backs the revealed bids
+	if numKeptBids == 0 {
+		errRefundDeposit := refundDeposit(ctx, auctionName, certFingerprint(bidderCertDer))
+		if errRefundDeposit != nil {
+			return fmt.Errorf("could not refund the deposit: %v", errRefundDeposit)
+		}
+	}
+
+	// Inform the users about the struck bids
+	errStrikeEvent := setStrikeEvent(ctx, auction, bidderCertDer, numStruckBids)
+	if errStrikeEvent != nil {
+		return fmt.Errorf("could not set strike event: %v", errStrikeEvent)
 	}
 
 	return nil
 }
+
+// SetDirectBuyPrice changes the direct buy price while the auction is open, e.g. to stimulate a sale
+// A price of 0 disables direct buy.
+func (s *VickreyAuctionContract) SetDirectBuyPrice(ctx contractapi.TransactionContextInterface, auctionName string, newPrice uint64) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Check if the submitting client is the seller of the auction
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "only the auction seller can set the direct buy price")
+	}
+
+	// Direct buy stays possible until the auction has ended, but the price is fixed once bidding has closed,
+	// so that it cannot be changed while the bidders reveal
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction is closed")
+	}
+
+	// The same rules as for a new auction apply
+	if newPrice != 0 {
+		if auction.Type == AuctionType(Dutch) || auctionQuantity(auction) > 1 {
+			return newAuctionError(ErrCodeInvalidArgument, "direct buy is not supported for this auction")
+		}
+		if auction.MinBidPrice > newPrice {
+			return newAuctionError(ErrCodeInvalidArgument, "direct buy price cannot be lower than the minimum bid price")
+		}
+		if auction.HasReserve && auction.ReservePrice > newPrice {
+			return newAuctionError(ErrCodeInvalidArgument, "direct buy price cannot be lower than the reserve price")
+		}
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Save the updated auction
+	auction.DirectBuyPrice = newPrice
+	errPutAuction := putAuction(ctx, auction)
+	if errPutAuction != nil {
+		return fmt.Errorf("could not save the updated auction: %v", errPutAuction)
+	}
+
+	// Inform the users about the new price
+	auctionSummaryErr := setAuctionSummaryEvent(ctx, newAuctionSummary(auction, bids))
+	if auctionSummaryErr != nil {
+		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	}
+
+	return nil
+}
+
+/**************** AUCTION BIDDER METHODS ****************/
+
+// Bid is called by a bidder to submit a hidden bid
+// Apparently, it is not possible to pass a byte array to the contract,
+// therefore the client has to send the hidden commit hex encoded.
+func (s *VickreyAuctionContract) Bid(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommitHex string) error {
+	return submitBid(ctx, auctionName, hiddenCommitHex, nil)
+}
+
+// BidSigned is like Bid, but the bidder also signs the hidden commit with the key of their certificate
+// The signature is stored with the bid, so that the bidder cannot deny the commitment later.
+// It is a hex encoded ASN.1 ECDSA signature of the SHA-256 hash of the message built by bidSignatureMessage.
+func (s *VickreyAuctionContract) BidSigned(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommitHex string, signatureHex string) error {
+	signature, errDecode := hex.DecodeString(signatureHex)
+	if errDecode != nil || len(signature) == 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "could not decode the signature")
+	}
+	return submitBid(ctx, auctionName, hiddenCommitHex, signature)
+}
+
+// submitBid stores a new hidden bid, the signature is nil for unsigned bids
+func submitBid(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommitHex string, signature []byte) error {
+	hiddenCommit, errDecode := decodeHiddenCommit(hiddenCommitHex)
+	if errDecode != nil {
+		return errDecode
+	}
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// OpenBid and EndAuction rely on the stored certificate being valid DER, so check it before storing the bid
+	_, errParseCert := x509.ParseCertificate(clientID.Raw)
+	if errParseCert != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the client certificate cannot be parsed: %v", errParseCert)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	errBidder := checkBidderAllowed(ctx, auction, clientID.Raw)
+	if errBidder != nil {
+		return errBidder
+	}
+	errSealedBids := checkSealedBids(auction)
+	if errSealedBids != nil {
+		return errSealedBids
+	}
+	errDifficulty := checkCommitDifficulty(auction, hiddenCommit)
+	if errDifficulty != nil {
+		return errDifficulty
+	}
+
+	// A signature binds the hidden commit to the key of the bidder's certificate
+	if signature == nil {
+		if auction.RequireBidSignature {
+			return newAuctionError(ErrCodeInvalidArgument, "bids of this auction must be signed, use BidSigned")
+		}
+	} else {
+		errSignature := verifyBidSignature(clientID, auctionName, hiddenCommit, signature)
+		if errSignature != nil {
+			return newAuctionError(ErrCodeInvalidArgument, "invalid bid signature: %v", errSignature)
+		}
+	}
+
+	// A resubmission of the same hidden commit, e.g. a retry after a network error, does not add another bid
+	// The hidden commit includes the certificate of the bidder, so the existing bid is the client's own.
+	existingBid, errFindBid := findBidByCommit(ctx, auctionName, hiddenCommit)
+	if errFindBid != nil {
+		return fmt.Errorf("could not look up the hidden commit: %v", errFindBid)
+	}
+	if existingBid != nil && reflect.DeepEqual(existingBid.Buyer, clientID.Raw) {
+		return nil
+	}
+
+	// Count the bids for the limit and the bid event, unless the auction has no limit and hides the count
+	// Counting the bids reads all bid keys, so concurrent bids may fail validation and must be resubmitted.
+	numBids := 0
+	if auction.MaxBids != 0 || !auction.HideBidCount {
+		var errCountBids error
+		numBids, errCountBids = countBids(ctx, auctionName)
+		if errCountBids != nil {
+			return wrapError(errCountBids, "could not count the bids")
+		}
+	}
+
+	// Limit the number of bids, if the seller asked for it
+	if auction.MaxBids != 0 && numBids >= int(auction.MaxBids) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction has reached the maximum of %d bids", auction.MaxBids)
+	}
+
+	// The bidder must lock a deposit, which backs their bid if they win
+	errLockDeposit := lockDeposit(ctx, auction, certFingerprint(clientID.Raw))
+	if errLockDeposit != nil {
+		if _, isAuctionError := errLockDeposit.(*AuctionError); isAuctionError {
+			return errLockDeposit
+		}
+		return fmt.Errorf("could not lock the deposit: %v", errLockDeposit)
+	}
+
+	// Record when the bid was submitted
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+
+	// Save the bid under its own key, the auction itself is not modified
+	bid := Bid{
+		ID:           ctx.GetStub().GetTxID(),
+		Buyer:        clientID.Raw,
+		BidPrice:     0,
+		HiddenCommit: hiddenCommit,
+		Timestamp:    timestamp,
+		Signature:    signature,
+	}
+	errPutBid := putBid(ctx, auctionName, &bid)
+	if errPutBid != nil {
+		return fmt.Errorf("could not save the bid: %v", errPutBid)
+	}
+	errCommitIndex := putBidCommitIndex(ctx, auctionName, &bid)
+	if errCommitIndex != nil {
+		return fmt.Errorf("could not index the hidden commit: %v", errCommitIndex)
+	}
+
+	// Inform the users about the new bid
+	bidEventErr := setBidEvent(ctx, newBidEvent(auction, &bid, numBids+1))
+	if bidEventErr != nil {
+		return fmt.Errorf("could not set bid event: %v", bidEventErr)
+	}
+
+	return nil
+}
+
+// ReplaceBid replaces the hidden bids of the submitting client with a new hidden commit while the auction is open
+// The client keeps a single bid with the new commit. If the client has no bid yet, a new bid is submitted like with Bid.
+func (s *VickreyAuctionContract) ReplaceBid(ctx contractapi.TransactionContextInterface, auctionName string, hiddenCommitHex string) error {
+	hiddenCommit, errDecode := decodeHiddenCommit(hiddenCommitHex)
+	if errDecode != nil {
+		return errDecode
+	}
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	errBidder := checkBidderAllowed(ctx, auction, clientID.Raw)
+	if errBidder != nil {
+		return errBidder
+	}
+	errSealedBids := checkSealedBids(auction)
+	if errSealedBids != nil {
+		return errSealedBids
+	}
+	errDifficulty := checkCommitDifficulty(auction, hiddenCommit)
+	if errDifficulty != nil {
+		return errDifficulty
+	}
+
+	// A replaced commit would not be covered by the signature
+	if auction.RequireBidSignature {
+		return newAuctionError(ErrCodeInvalidStatus, "bids of this auction must be signed, they cannot be replaced")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+	myBids := []Bid{}
+	for i := range bids {
+		if reflect.DeepEqual(bids[i].Buyer, clientID.Raw) {
+			myBids = append(myBids, bids[i])
+		}
+	}
+
+	// A first-time bidder has nothing to replace
+	if len(myBids) == 0 {
+		return s.Bid(ctx, auctionName, hiddenCommitHex)
+	}
+
+	// Record when the bid was replaced
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+
+	// Keep the first bid with the new commit and remove the others, the deposit stays locked
+	// A signature of the old commit does not cover the new one, so it is dropped
+	bid := &myBids[0]
+	bid.HiddenCommit = hiddenCommit
+	bid.Signature = nil
+	bid.Timestamp = timestamp
+	errPutBid := putBid(ctx, auctionName, bid)
+	if errPutBid != nil {
+		return fmt.Errorf("could not save the bid: %v", errPutBid)
+	}
+	errCommitIndex := putBidCommitIndex(ctx, auctionName, bid)
+	if errCommitIndex != nil {
+		return fmt.Errorf("could not index the hidden commit: %v", errCommitIndex)
+	}
+	for _, otherBid := range myBids[1:] {
+		errDeleteBid := deleteBid(ctx, auctionName, otherBid.ID)
+		if errDeleteBid != nil {
+			return fmt.Errorf("could not delete the bid: %v", errDeleteBid)
+		}
+	}
+
+	// Inform the users about the changed bid, the other bids of the bidder were removed
+	numBids := len(bids) - len(myBids) + 1
+	bidEventErr := setBidEvent(ctx, newBidEvent(auction, bid, numBids))
+	if bidEventErr != nil {
+		return fmt.Errorf("could not set bid event: %v", bidEventErr)
+	}
+
+	return nil
+}
+
+// OpenBid reveals the bid price of a bid
+func (s *VickreyAuctionContract) OpenBid(ctx contractapi.TransactionContextInterface, auctionName string, bidPrice uint64, saltHex string) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction and bids from world state
+	auction, bids, errRevealable := getRevealableAuction(ctx, auctionName, clientID.Raw)
+	if errRevealable != nil {
+		return errRevealable
+	}
+
+	errReveal := revealBid(ctx, auction, bids, clientID, bidPrice, saltHex)
+	if errReveal != nil {
+		return errReveal
+	}
+
+	// Inform the users about the reveal
+	errRevealEvent := setRevealEvent(ctx, auction, clientID.Raw, []uint64{bidPrice})
+	if errRevealEvent != nil {
+		return fmt.Errorf("could not set reveal event: %v", errRevealEvent)
+	}
+
+	return nil
+}
+
+// OpenBids reveals several bids of the submitting client in one transaction
+// Reveals which fail do not stop the others, the result tells for each reveal whether it succeeded.
+func (s *VickreyAuctionContract) OpenBids(ctx contractapi.TransactionContextInterface, auctionName string, reveals []BidReveal) ([]RevealResult, error) {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction and bids from world state
+	auction, bids, errRevealable := getRevealableAuction(ctx, auctionName, clientID.Raw)
+	if errRevealable != nil {
+		return nil, errRevealable
+	}
+
+	// The bids are updated in memory, so later reveals see the bids revealed by earlier ones
+	results := make([]RevealResult, 0, len(reveals))
+	revealedPrices := []uint64{}
+	for _, reveal := range reveals {
+		result := RevealResult{
+			BidPrice: reveal.BidPrice,
+			Revealed: true,
+			Error:    "",
+		}
+		errReveal := revealBid(ctx, auction, bids, clientID, reveal.BidPrice, reveal.Salt)
+		if errReveal != nil {
+			if _, isAuctionError := errReveal.(*AuctionError); !isAuctionError {
+				return nil, errReveal
+			}
+			result.Revealed = false
+			result.Error = errReveal.Error()
+		} else {
+			revealedPrices = append(revealedPrices, reveal.BidPrice)
+		}
+		results = append(results, result)
+	}
+
+	// A transaction can only set one event, so it covers all successful reveals
+	if len(revealedPrices) != 0 {
+		errRevealEvent := setRevealEvent(ctx, auction, clientID.Raw, revealedPrices)
+		if errRevealEvent != nil {
+			return nil, fmt.Errorf("could not set reveal event: %v", errRevealEvent)
+		}
+	}
+
+	return results, nil
+}
+
+// WithdrawBid removes all unrevealed bids of the submitting client while the auction is open
+func (s *VickreyAuctionContract) WithdrawBid(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Bids can only be withdrawn while the auction is open
+	if auction.Status != AuctionStatus(Open) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction is closed")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Delete the unrevealed bids of the client
+	numWithdrawnBids := 0
+	for i := range bids {
+		bid := &bids[i]
+		if reflect.DeepEqual(bid.Buyer, clientID.Raw) && bid.BidPrice == 0 {
+			errDeleteBid := deleteBid(ctx, auctionName, bid.ID)
+			if errDeleteBid != nil {
+				return fmt.Errorf("could not delete the bid: %v", errDeleteBid)
+			}
+			numWithdrawnBids += 1
+		}
+	}
+	if numWithdrawnBids == 0 {
+		return newAuctionError(ErrCodeNotFound, "no unrevealed bids to withdraw")
+	}
+
+	// Bids are only revealed after the auction was closed, so the client has no bids left
+	errRefundDeposit := refundDeposit(ctx, auctionName, certFingerprint(clientID.Raw))
+	if errRefundDeposit != nil {
+		return fmt.Errorf("could not refund the deposit: %v", errRefundDeposit)
+	}
+
+	return nil
+}
+
+// DirectBuy: The buyer should pay at least auction.DirectBuyPrice to directly purchase the auction item
+// The hammer price is always the direct buy price, offering more than that does not make the buyer pay more.
+// A non-zero expectedVersion must match the version of the auction, e.g. to make sure the direct buy price has not changed.
+func (s *VickreyAuctionContract) DirectBuy(ctx contractapi.TransactionContextInterface, auctionName string, price uint64, expectedVersion uint64) error {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Check auction status
+	if auction.Status == AuctionStatus(Ended) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction has already ended")
+	}
+
+	errVersion := checkExpectedVersion(auction, expectedVersion)
+	if errVersion != nil {
+		return errVersion
+	}
+
+	// The seller must not buy the item of their own auction
+	if reflect.DeepEqual(clientID.Raw, auction.Seller) {
+		return newAuctionError(ErrCodePermissionDenied, "seller cannot bid on their own auction")
+	}
+
+	// Check if the client's organization may participate
+	errMSP := checkClientMSPAllowed(ctx, auction.AllowedMSPs)
+	if errMSP != nil {
+		return errMSP
+	}
+
+	// Check if the client is invited, if the auction is private
+	if !isBidderInvited(auction, clientID.Raw) {
+		return newAuctionError(ErrCodePermissionDenied, "the client is not invited to this auction")
+	}
+
+	// Check direct buy validity
+	if auction.DirectBuyPrice == 0 {
+		return newAuctionError(ErrCodeInvalidStatus, "direct buy is disabled for this auction")
+	}
+	if price < auction.DirectBuyPrice {
+		return newAuctionError(ErrCodeInvalidArgument, "payment amount not sufficient for a direct buy")
+	}
+
+	// End the auction
+	auction.HammerPrice = auction.DirectBuyPrice
+	auction.Winner = clientID.Raw
+	auction.Status = AuctionStatus(Ended)
+	auction.Result = &AuctionResult{
+		Winner:      auction.Winner,
+		Winners:     [][]byte{auction.Winner},
+		HammerPrice: auction.HammerPrice,
+		DirectBuy:   true,
+		Currency:    auction.Currency,
+	}
+	refunds, fee, errSettleDeposits := settleDeposits(ctx, auction)
+	if errSettleDeposits != nil {
+		return fmt.Errorf("could not settle the deposits: %v", errSettleDeposits)
+	}
+	auction.Result.Refunds = refunds
+	auction.Result.Fee = fee
+	errPutAuction := putAuction(ctx, auction)
+	if errPutAuction != nil {
+		return fmt.Errorf("could not save ended auction: %v", errPutAuction)
+	}
+	errItemRefIndex := removeItemRefIndex(ctx, auction)
+	if errItemRefIndex != nil {
+		return fmt.Errorf("could not remove the auction from the item reference index: %v", errItemRefIndex)
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Inform the users about the auction result
+	// Bidders with hidden bids learn from the summary that they do not need to reveal them anymore.
+	auctionSummary := newAuctionSummary(auction, bids)
+	for i := range bids {
+		if bids[i].BidPrice == 0 {
+			auctionSummary.NumVoidedBids += 1
+		}
+	}
+	auctionSummaryErr := setAuctionSummaryEvent(ctx, auctionSummary)
+	if auctionSummaryErr != nil {
+		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	}
+
+	return nil
+}
+
+// PlaceBid submits an open bid to an English or unsealed auction
+// In an English auction, the bid must outbid the standing high bid by at least the bid increment, and the hammer price follows the high bid.
+// The seller finalizes the auction with CloseAuction and EndAuction, the highest bidder wins and pays their bid.
+// Unsealed auctions of the other types end like sealed ones, as if every bid had been revealed.
+func (s *VickreyAuctionContract) PlaceBid(ctx contractapi.TransactionContextInterface, auctionName string, price uint64) error {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// EndAuction relies on the stored certificate being valid DER, so check it before storing the bid
+	_, errParseCert := x509.ParseCertificate(clientID.Raw)
+	if errParseCert != nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the client certificate cannot be parsed: %v", errParseCert)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if !takesOpenBids(auction) {
+		return newAuctionError(ErrCodeInvalidArgument, "open bids can only be placed in English and unsealed auctions")
+	}
+	errBidder := checkBidderAllowed(ctx, auction, clientID.Raw)
+	if errBidder != nil {
+		return errBidder
+	}
+
+	// Open bids are checked like revealed ones
+	errBidPrice := checkBidPrice(auction, price)
+	if errBidPrice != nil {
+		return errBidPrice
+	}
+
+	// The bid must beat the standing high bid of an English auction
+	english := auction.Type == AuctionType(English)
+	if english {
+		minimumBid, errMinimumBid := englishMinimumBid(auction)
+		if errMinimumBid != nil {
+			return errMinimumBid
+		}
+		if price < minimumBid {
+			return newAuctionError(ErrCodeInvalidArgument, "bid must be at least %d", minimumBid)
+		}
+	}
+
+	// Count the bids for the limit and the bid event, unless the auction has no limit and hides the count
+	numBids := 0
+	if auction.MaxBids != 0 || !auction.HideBidCount {
+		var errCountBids error
+		numBids, errCountBids = countBids(ctx, auctionName)
+		if errCountBids != nil {
+			return wrapError(errCountBids, "could not count the bids")
+		}
+	}
+
+	// Open bids count against the limit like sealed ones
+	if auction.MaxBids != 0 && numBids >= int(auction.MaxBids) {
+		return newAuctionError(ErrCodeInvalidStatus, "auction has reached the maximum of %d bids", auction.MaxBids)
+	}
+
+	// The bidder must lock a deposit, which backs their bid if they win
+	errLockDeposit := lockDeposit(ctx, auction, certFingerprint(clientID.Raw))
+	if errLockDeposit != nil {
+		if _, isAuctionError := errLockDeposit.(*AuctionError); isAuctionError {
+			return errLockDeposit
+		}
+		return fmt.Errorf("could not lock the deposit: %v", errLockDeposit)
+	}
+
+	// Record when the bid was submitted
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+
+	// Open bids are stored like revealed sealed bids, so EndAuction can determine the winner the same way
+	bid := Bid{
+		ID:           ctx.GetStub().GetTxID(),
+		Buyer:        clientID.Raw,
+		BidPrice:     price,
+		HiddenCommit: nil,
+		Timestamp:    timestamp,
+	}
+	errPutBid := putBid(ctx, auctionName, &bid)
+	if errPutBid != nil {
+		return fmt.Errorf("could not save the bid: %v", errPutBid)
+	}
+	if english {
+		auction.HammerPrice = price
+		auction.HighBidder = clientID.Raw
+		errPutAuction := putAuction(ctx, auction)
+		if errPutAuction != nil {
+			return fmt.Errorf("could not save the auction: %v", errPutAuction)
+		}
+	}
+
+	// Inform the users about the new bid
+	bidEvent := newBidEvent(auction, &bid, numBids+1)
+	bidEvent.Price = price
+	bidEventErr := setBidEvent(ctx, bidEvent)
+	if bidEventErr != nil {
+		return fmt.Errorf("could not set bid event: %v", bidEventErr)
+	}
+
+	return nil
+}
+
+// Accept buys the item of a Dutch auction at the current clock price
+// The clock price is derived from the transaction timestamp, so the first accepting transaction which is committed wins.
+func (s *VickreyAuctionContract) Accept(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Type != AuctionType(Dutch) {
+		return newAuctionError(ErrCodeInvalidArgument, "only the clock price of Dutch auctions can be accepted")
+	}
+	errBidder := checkBidderAllowed(ctx, auction, clientID.Raw)
+	if errBidder != nil {
+		return errBidder
+	}
+
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+	price := dutchClockPrice(auction, timestamp)
+	if price == 0 {
+		return newAuctionError(ErrCodeInvalidStatus, "the clock price has run down to zero")
+	}
+
+	// End the auction
+	auction.HammerPrice = price
+	auction.Winner = clientID.Raw
+	auction.Status = AuctionStatus(Ended)
+	auction.Result = &AuctionResult{
+		Winner:      auction.Winner,
+		Winners:     [][]byte{auction.Winner},
+		HammerPrice: auction.HammerPrice,
+		DirectBuy:   false,
+		Currency:    auction.Currency,
+	}
+	errPutAuction := putAuction(ctx, auction)
+	if errPutAuction != nil {
+		return fmt.Errorf("could not save ended auction: %v", errPutAuction)
+	}
+	errItemRefIndex := removeItemRefIndex(ctx, auction)
+	if errItemRefIndex != nil {
+		return fmt.Errorf("could not remove the auction from the item reference index: %v", errItemRefIndex)
+	}
+
+	// Inform the users about the auction result
+	auctionSummaryErr := setAuctionSummaryEvent(ctx, newAuctionSummary(auction, []Bid{}))
+	if auctionSummaryErr != nil {
+		return fmt.Errorf("could not set auction summary event: %v", auctionSummaryErr)
+	}
+
+	return nil
+}
+
+// Deposit adds the given amount to the balance of the submitting client
+// The balance is a simple on-ledger account which backs the deposits of auctions with a minimum deposit.
+// How the amount is paid in is outside of the scope of this contract.
+func (s *VickreyAuctionContract) Deposit(ctx contractapi.TransactionContextInterface, amount uint64) error {
+	if amount == 0 {
+		return newAuctionError(ErrCodeInvalidArgument, "amount cannot be zero")
+	}
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	errAddBalance := addBalance(ctx, certFingerprint(clientID.Raw), amount)
+	if errAddBalance != nil {
+		return fmt.Errorf("could not update the balance: %v", errAddBalance)
+	}
+
+	return nil
+}
+
+/**************** ADMIN METHODS ****************/
+
+// InitLedger records the submitting client as the admin of the contract
+// It can only be called once, so it should be invoked by the deployer right after the chaincode is committed
+// (e.g. with --init-required and --isInit), before anybody else can claim the admin role.
+func (s *VickreyAuctionContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	admin, errGetAdmin := getAdmin(ctx)
+	if errGetAdmin != nil {
+		return fmt.Errorf("could not get the admin: %v", errGetAdmin)
+	}
+	if admin != nil {
+		return newAuctionError(ErrCodeAlreadyExists, "the admin has already been set")
+	}
+
+	errPutAdmin := ctx.GetStub().PutState(adminKey, clientID.Raw)
+	if errPutAdmin != nil {
+		return fmt.Errorf("could not save the admin: %v", errPutAdmin)
+	}
+
+	return nil
+}
+
+// SetAdmin hands the admin role over to the client with the given PEM encoded certificate
+// Only the current admin can call it.
+func (s *VickreyAuctionContract) SetAdmin(ctx contractapi.TransactionContextInterface, adminPem string) error {
+	errAdmin := checkAdmin(ctx)
+	if errAdmin != nil {
+		return errAdmin
+	}
+
+	adminCertDer := certPemToDer(adminPem)
+	if adminCertDer == nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	errPutAdmin := ctx.GetStub().PutState(adminKey, adminCertDer)
+	if errPutAdmin != nil {
+		return fmt.Errorf("could not save the admin: %v", errPutAdmin)
+	}
+
+	return nil
+}
+
+// SetMaxAuctionNameLength sets the maximum length of new auction names in bytes
+// Only the admin can call it. A length of 0 restores the default, existing auctions keep their names.
+func (s *VickreyAuctionContract) SetMaxAuctionNameLength(ctx contractapi.TransactionContextInterface, maxLength uint32) error {
+	errAdmin := checkAdmin(ctx)
+	if errAdmin != nil {
+		return errAdmin
+	}
+
+	if maxLength == 0 {
+		errDelete := ctx.GetStub().DelState(maxAuctionNameLengthKey)
+		if errDelete != nil {
+			return fmt.Errorf("could not delete the maximum auction name length: %v", errDelete)
+		}
+		return nil
+	}
+	if maxLength > upperMaxAuctionNameLength {
+		return newAuctionError(ErrCodeInvalidArgument, "the maximum auction name length must be at most %d bytes", upperMaxAuctionNameLength)
+	}
+
+	maxLengthJSON, errMarshal := json.Marshal(maxLength)
+	if errMarshal != nil {
+		return fmt.Errorf("failed to marshal the maximum auction name length: %v", errMarshal)
+	}
+	errPut := ctx.GetStub().PutState(maxAuctionNameLengthKey, maxLengthJSON)
+	if errPut != nil {
+		return fmt.Errorf("could not save the maximum auction name length: %v", errPut)
+	}
+
+	return nil
+}
+
+// SetFeeRecipient sets the platform identity whose balance receives the fees of new auctions
+// Only the admin can call it. Auctions which were already created keep their fee recipient.
+func (s *VickreyAuctionContract) SetFeeRecipient(ctx contractapi.TransactionContextInterface, recipientPem string) error {
+	errAdmin := checkAdmin(ctx)
+	if errAdmin != nil {
+		return errAdmin
+	}
+
+	recipient := certPemToDer(recipientPem)
+	if recipient == nil {
+		return newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	errPut := ctx.GetStub().PutState(feeRecipientKey, recipient)
+	if errPut != nil {
+		return fmt.Errorf("could not save the fee recipient: %v", errPut)
+	}
+
+	return nil
+}
+
+// GetMaxAuctionNameLength returns the maximum length of new auction names in bytes
+func (s *VickreyAuctionContract) GetMaxAuctionNameLength(ctx contractapi.TransactionContextInterface) (uint32, error) {
+	maxLength, errMaxLength := getMaxAuctionNameLength(ctx)
+	if errMaxLength != nil {
+		return 0, fmt.Errorf("could not get the maximum auction name length: %v", errMaxLength)
+	}
+	return uint32(maxLength), nil
+}
+
+// PurgeEndedAuction deletes an ended auction and its bids from the world state
+// Only the admin can call it. The auction stays in the history database and a purge event with its last summary is set.
+func (s *VickreyAuctionContract) PurgeEndedAuction(ctx contractapi.TransactionContextInterface, auctionName string) error {
+	errAdmin := checkAdmin(ctx)
+	if errAdmin != nil {
+		return errAdmin
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Unfinished auctions still hold bids and deposits which are needed
+	if auction.Status != AuctionStatus(Ended) {
+		return newAuctionError(ErrCodeInvalidStatus, "only ended auctions can be purged")
+	}
+
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return wrapError(errGetBids, "could not get the bids")
+	}
+	for _, bid := range bids {
+		errDeleteBid := deleteBid(ctx, auctionName, bid.ID)
+		if errDeleteBid != nil {
+			return fmt.Errorf("could not delete the bid: %v", errDeleteBid)
+		}
+	}
+	errCommitIndex := deleteBidCommitIndex(ctx, auctionName)
+	if errCommitIndex != nil {
+		return fmt.Errorf("could not delete the hidden commit index: %v", errCommitIndex)
+	}
+	errDeleteAuction := deleteAuction(ctx, auctionName)
+	if errDeleteAuction != nil {
+		return fmt.Errorf("could not delete the auction: %v", errDeleteAuction)
+	}
+
+	errPurgeEvent := setPurgeEvent(ctx, newAuctionSummary(auction, bids))
+	if errPurgeEvent != nil {
+		return fmt.Errorf("could not set purge event: %v", errPurgeEvent)
+	}
+
+	return nil
+}
+
+// MigrateBids moves the bids which an earlier version of the chaincode stored inside the auction to their own keys
+// Until then, all transactions and queries which read the bids of the auction fail with INVALID_STATUS.
+// The seller or the admin can call it. The moved bids get the ID of this transaction followed by their index.
+func (s *VickreyAuctionContract) MigrateBids(ctx contractapi.TransactionContextInterface, auctionName string) error {
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Check if the submitting client is the seller of the auction or the admin
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		errAdmin := checkAdmin(ctx)
+		if errAdmin != nil {
+			return newAuctionError(ErrCodePermissionDenied, "only the auction seller or the admin can migrate the bids")
+		}
+	}
+
+	if len(auction.LegacyBids) == 0 {
+		return newAuctionError(ErrCodeNotFound, "the auction has no bids stored inline")
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	for i := range auction.LegacyBids {
+		bid := &auction.LegacyBids[i]
+		bid.ID = fmt.Sprintf("%s.%d", txID, i)
+		errPutBid := putBid(ctx, auctionName, bid)
+		if errPutBid != nil {
+			return fmt.Errorf("could not save the bid: %v", errPutBid)
+		}
+		// Scrubbed bids have no hidden commit anymore
+		if bid.HiddenCommit != nil {
+			errCommitIndex := putBidCommitIndex(ctx, auctionName, bid)
+			if errCommitIndex != nil {
+				return fmt.Errorf("could not index the hidden commit: %v", errCommitIndex)
+			}
+		}
+	}
+
+	auction.LegacyBids = nil
+	errPutAuction := putAuction(ctx, auction)
+	if errPutAuction != nil {
+		return fmt.Errorf("could not save the updated auction: %v", errPutAuction)
+	}
+
+	return nil
+}
+
+/**************** AUCTION QUERY METHODS ****************/
+
+// GetAuctionEndorsementPolicy returns the orgs which can endorse changes of the auction and how many of them must endorse
+// A policy without orgs means that only the chaincode endorsement policy applies.
+func (s *VickreyAuctionContract) GetAuctionEndorsementPolicy(ctx contractapi.TransactionContextInterface, auctionName string) (*EndorsementPolicy, error) {
+	auctionExists, errAuctionExist := doesAuctionExist(ctx, auctionName)
+	if errAuctionExist != nil {
+		return nil, fmt.Errorf("failed to check if the auction exists: %v", errAuctionExist)
+	}
+	if !auctionExists {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	endorsementPolicy, errEndorsementPolicy := getAuctionEndorsementPolicy(ctx, auctionName)
+	if errEndorsementPolicy != nil {
+		return nil, fmt.Errorf("could not get the auction endorsement policy: %v", errEndorsementPolicy)
+	}
+	return endorsementPolicy, nil
+}
+
+// FindAuctionsByItemRef returns the names of the unfinished auctions selling the given item
+func (s *VickreyAuctionContract) FindAuctionsByItemRef(ctx contractapi.TransactionContextInterface, itemRef string) ([]string, error) {
+	if itemRef == "" {
+		return nil, fmt.Errorf("item reference cannot be empty")
+	}
+	auctionNames, err := getAuctionsByItemRef(ctx, itemRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up auctions by item reference: %v", err)
+	}
+	return auctionNames, nil
+}
+
+// ComputeBidCommitment computes the hex encoded hidden commit of a bid of the submitting client
+// Clients can evaluate it to get exactly the commitment which OpenBid verifies.
+// As the salt is sent to the peer, the transaction should only be evaluated on a trusted peer and never be submitted.
+func (s *VickreyAuctionContract) ComputeBidCommitment(ctx contractapi.TransactionContextInterface, bidPrice uint64, saltHex string) (string, error) {
+	// Decode salt
+	salt, errSaltDecode := hex.DecodeString(saltHex)
+	if errSaltDecode != nil {
+		return "", fmt.Errorf("could not decode salt: %v", errSaltDecode)
+	}
+
+	// Check salt minimum requirements, so that the bid can be opened later
+	if len(salt) < minSaltLength {
+		return "", fmt.Errorf("salt should be at least %d bytes long", minSaltLength)
+	}
+
+	clientCert, errCert := ctx.GetClientIdentity().GetX509Certificate()
+	if errCert != nil {
+		return "", fmt.Errorf("could not get client certificate")
+	}
+
+	bidHash, errHashBid := hashBid(clientCert, bidPrice, salt)
+	if errHashBid != nil {
+		return "", errHashBid
+	}
+
+	return hex.EncodeToString(bidHash), nil
+}
+
+// GetCommitmentPreimageLayout describes the exact byte layout which is hashed to compute a hidden commit of the auction
+// Client libraries can use it to construct the preimage exactly like hashBid does.
+func (s *VickreyAuctionContract) GetCommitmentPreimageLayout(ctx contractapi.TransactionContextInterface, auctionName string) (*CommitmentPreimageLayout, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// The fields are in the order in which hashBid writes them to the SHAKE256 instance
+	return &CommitmentPreimageLayout{
+		FormatVersion: bidCommitmentFormat,
+		HashFunction:  "SHAKE256",
+		OutputLength:  bidCommitmentLength,
+		Fields: []PreimageField{
+			{
+				Name:      "clientCert",
+				Encoding:  "X.509 certificate of the bidder in DER format",
+				Length:    0,
+				MinLength: 1,
+			},
+			{
+				Name:      "bidPrice",
+				Encoding:  "unsigned 64 bit integer, big endian",
+				Length:    8,
+				MinLength: 8,
+			},
+			{
+				Name:      "salt",
+				Encoding:  "random bytes",
+				Length:    0,
+				MinLength: auctionMinSaltLength(auction),
+			},
+		},
+		LeadingZeros: int(auction.CommitDifficulty),
+	}, nil
+}
+
+// GetAuctionResult returns the result of an ended auction as JSON, like in the auction summary event
+func (s *VickreyAuctionContract) GetAuctionResult(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status != AuctionStatus(Ended) {
+		return "", newAuctionError(ErrCodeInvalidStatus, "auction has not ended yet")
+	}
+
+	return marshalQueryResult(auctionResult(auction))
+}
+
+// PreviewResult computes the result which EndAuction would produce, without changing the auction
+// Ties for the last units are not broken, the preview reports the tied bidders instead.
+// Like EndAuction, it requires a closed auction whose bids are all revealed.
+func (s *VickreyAuctionContract) PreviewResult(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status != AuctionStatus(Closed) {
+		return "", newAuctionError(ErrCodeInvalidStatus, "only the result of a closed auction can be previewed")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return "", wrapError(errGetBids, "could not get the bids")
+	}
+
+	outcome, errOutcome := computeAuctionOutcome(auction, bids)
+	if errOutcome != nil {
+		return "", errOutcome
+	}
+
+	// Without a tie, all candidates win
+	preview := ResultPreview{
+		Result:       nil,
+		TiedBidders:  [][]byte{},
+		NumTiedUnits: 0,
+	}
+	if outcome.numCandidateUnits == uint64(len(outcome.candidates)) {
+		preview.Result = outcome.result(outcome.candidates)
+	} else {
+		preview.Result = outcome.result(nil)
+		preview.TiedBidders = outcome.candidates
+		preview.NumTiedUnits = outcome.numCandidateUnits
+	}
+
+	return marshalQueryResult(preview)
+}
+
+// GetAuctionJSON returns a client-friendly view of an auction and its bids
+// Unlike the stored auction, it contains PEM certificates and hex encoded hidden commits, the latter only after the auction was closed.
+// Auctions with HideBidCount list their bids only after they have ended.
+func (s *VickreyAuctionContract) GetAuctionJSON(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return "", wrapError(errGetBids, "could not get the bids")
+	}
+
+	return marshalQueryResult(newAuctionView(auction, bids))
+}
+
+// VerifyMyCommitment checks whether the bid price and salt match one of the unrevealed bids of the submitting client
+// Clients can evaluate it before submitting OpenBid. Like ComputeBidCommitment, it should only be evaluated on a trusted peer.
+func (s *VickreyAuctionContract) VerifyMyCommitment(ctx contractapi.TransactionContextInterface, auctionName string, bidPrice uint64, saltHex string) (bool, error) {
+	// Decode salt
+	salt, errSaltDecode := hex.DecodeString(saltHex)
+	if errSaltDecode != nil {
+		return false, fmt.Errorf("could not decode salt: %v", errSaltDecode)
+	}
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return false, fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return false, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return false, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return false, wrapError(errGetBids, "could not get the bids")
+	}
+
+	bidHash, errHashBid := hashBid(clientID, bidPrice, salt)
+	if errHashBid != nil {
+		return false, errHashBid
+	}
+
+	for i := range bids {
+		bid := &bids[i]
+		if reflect.DeepEqual(bid.Buyer, clientID.Raw) && bid.BidPrice == 0 && reflect.DeepEqual(bid.HiddenCommit, bidHash) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetWinner returns the winner of an ended auction with the hammer price and whether the item was bought directly
+// For auctions with more than one unit, it returns the first winner, GetAuctionResult lists all of them.
+func (s *VickreyAuctionContract) GetWinner(ctx contractapi.TransactionContextInterface, auctionName string) (*WinnerInfo, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status != AuctionStatus(Ended) {
+		return nil, newAuctionError(ErrCodeInvalidStatus, "auction has not ended yet")
+	}
+	result := auctionResult(auction)
+	if len(result.Winner) == 0 {
+		return nil, newAuctionError(ErrCodeNotFound, "auction has no winner")
+	}
+
+	winnerCertPem := certDerToPem(result.Winner)
+	if winnerCertPem == nil {
+		return nil, fmt.Errorf("could not convert certificate from DER to PEM format")
+	}
+
+	return &WinnerInfo{
+		WinnerPem:   *winnerCertPem,
+		DirectBuy:   result.DirectBuy,
+		HammerPrice: result.HammerPrice,
+	}, nil
+}
+
+// GetMyBids returns the bids of the submitting client as a JSON array
+// A bid has been revealed successfully if its bid price is not 0.
+func (s *VickreyAuctionContract) GetMyBids(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return "", fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return "", wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Only return the client's own bids
+	myBids := []Bid{}
+	for i := range bids {
+		bid := &bids[i]
+		if reflect.DeepEqual(bid.Buyer, clientID.Raw) {
+			myBids = append(myBids, *bid)
+		}
+	}
+
+	return marshalQueryResult(myBids)
+}
+
+// GetAuctionStatus returns only the status of the auction, e.g. "open"
+// The status is returned as string, because the contract metadata would describe an AuctionStatus as integer.
+func (s *VickreyAuctionContract) GetAuctionStatus(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	return auction.Status.String(), nil
+}
+
+// IsTerminal returns true if the auction is in a state which will not change anymore, so that clients can stop polling
+// Cancelled auctions are removed from the world state, so they are reported as not found.
+func (s *VickreyAuctionContract) IsTerminal(ctx contractapi.TransactionContextInterface, auctionName string) (bool, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return false, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return false, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	return auction.Status.IsTerminal(), nil
+}
+
+// GetBidCount returns the number of bids submitted to an auction without revealing the hidden commits
+// Auctions with HideBidCount only return it after they have ended.
+func (s *VickreyAuctionContract) GetBidCount(ctx contractapi.TransactionContextInterface, auctionName string) (int, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return 0, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return 0, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+	errBidCount := checkBidCountVisible(auction)
+	if errBidCount != nil {
+		return 0, errBidCount
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return 0, wrapError(errGetBids, "could not get the bids")
+	}
+
+	return len(bids), nil
+}
+
+// GetRevealProgress returns how many bids of an auction have been revealed
+// EndAuction succeeds once all bids are revealed. Auctions with HideBidCount only return it after they have ended.
+func (s *VickreyAuctionContract) GetRevealProgress(ctx contractapi.TransactionContextInterface, auctionName string) (*RevealProgress, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+	errBidCount := checkBidCountVisible(auction)
+	if errBidCount != nil {
+		return nil, errBidCount
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return nil, wrapError(errGetBids, "could not get the bids")
+	}
+
+	progress := RevealProgress{
+		Revealed: 0,
+		Total:    len(bids),
+	}
+	for _, bid := range bids {
+		if bid.BidPrice != 0 {
+			progress.Revealed++
+		}
+	}
+	return &progress, nil
+}
+
+// HasBid returns whether the buyer with the given PEM encoded certificate has a bid in the auction
+// Withdrawn bids do not count. Auctions with HideBidCount only answer after they have ended,
+// because probing the bidders one by one would disclose the number of bids.
+func (s *VickreyAuctionContract) HasBid(ctx contractapi.TransactionContextInterface, auctionName string, buyerPem string) (bool, error) {
+	buyerCertDer := certPemToDer(buyerPem)
+	if buyerCertDer == nil {
+		return false, newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return false, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return false, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+	errBidCount := checkBidCountVisible(auction)
+	if errBidCount != nil {
+		return false, errBidCount
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return false, wrapError(errGetBids, "could not get the bids")
+	}
+
+	for i := range bids {
+		if reflect.DeepEqual(bids[i].Buyer, buyerCertDer) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetClockPrice returns the current clock price of an open Dutch auction
+// It is computed from the timestamp of the query, so the price of a later Accept can be lower.
+func (s *VickreyAuctionContract) GetClockPrice(ctx contractapi.TransactionContextInterface, auctionName string) (uint64, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return 0, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return 0, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Type != AuctionType(Dutch) {
+		return 0, newAuctionError(ErrCodeInvalidArgument, "only Dutch auctions have a clock price")
+	}
+	if auction.Status != AuctionStatus(Open) {
+		return 0, newAuctionError(ErrCodeInvalidStatus, "the clock only runs while the auction is open")
+	}
+
+	timestamp, errTimestamp := getTxTimestamp(ctx)
+	if errTimestamp != nil {
+		return 0, fmt.Errorf("could not get the transaction timestamp: %v", errTimestamp)
+	}
+
+	return dutchClockPrice(auction, timestamp), nil
+}
+
+// GetBidCommitment returns the hex encoded hidden commit of the earliest unrevealed bid of a buyer, e.g. for audits
+// Only the seller and the admin can query it, and only after bidding has closed.
+func (s *VickreyAuctionContract) GetBidCommitment(ctx contractapi.TransactionContextInterface, auctionName string, buyerPem string) (string, error) {
+	buyerCertDer := certPemToDer(buyerPem)
+	if buyerCertDer == nil {
+		return "", newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return "", fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if !reflect.DeepEqual(auction.Seller, clientID.Raw) {
+		errAdmin := checkAdmin(ctx)
+		if errAdmin != nil {
+			return "", errAdmin
+		}
+	}
+	if auction.Status == AuctionStatus(Open) {
+		return "", newAuctionError(ErrCodeInvalidStatus, "bid commitments can only be queried after bidding has closed")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return "", wrapError(errGetBids, "could not get the bids")
+	}
+
+	var earliestBid *Bid = nil
+	for i := range bids {
+		bid := &bids[i]
+		if !reflect.DeepEqual(bid.Buyer, buyerCertDer) || bid.BidPrice != 0 {
+			continue
+		}
+		if earliestBid == nil || bid.Timestamp < earliestBid.Timestamp {
+			earliestBid = bid
+		}
+	}
+	if earliestBid == nil {
+		return "", newAuctionError(ErrCodeNotFound, "the buyer has no unrevealed bid in the auction")
+	}
+
+	return hex.EncodeToString(earliestBid.HiddenCommit), nil
+}
+
+// GetHighestRevealedBid returns the highest eligible bid price revealed so far, or 0 if no such bid is revealed yet
+// It is not available while the auction is open, as no bid can be revealed before the auction is closed.
+func (s *VickreyAuctionContract) GetHighestRevealedBid(ctx contractapi.TransactionContextInterface, auctionName string) (uint64, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return 0, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return 0, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status == AuctionStatus(Open) {
+		return 0, newAuctionError(ErrCodeInvalidStatus, "the highest revealed bid is only available after the auction was closed")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return 0, wrapError(errGetBids, "could not get the bids")
+	}
+
+	highestBidPrice := uint64(0)
+	for i := range bids {
+		if !bids[i].Ineligible && bids[i].BidPrice > highestBidPrice {
+			highestBidPrice = bids[i].BidPrice
+		}
+	}
+
+	return highestBidPrice, nil
+}
+
+// GetCommitmentSet returns the hidden commits of all bids together with the bidders' certificate fingerprints
+// It is only available after the auction was closed, so that it cannot help anyone to adapt their bids.
+// Auditors can use it to verify the reveals independently. Auctions with anonymous IDs list those instead of
+// the fingerprints, and bids which were scrubbed after the end are left out. Auctions with HideBidCount only return it after they have ended.
+func (s *VickreyAuctionContract) GetCommitmentSet(ctx contractapi.TransactionContextInterface, auctionName string) ([]BidCommitment, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status == AuctionStatus(Open) {
+		return nil, newAuctionError(ErrCodeInvalidStatus, "the commitment set is only available after the auction was closed")
+	}
+	errBidCount := checkBidCountVisible(auction)
+	if errBidCount != nil {
+		return nil, errBidCount
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return nil, wrapError(errGetBids, "could not get the bids")
+	}
+
+	commitments := make([]BidCommitment, 0, len(bids))
+	for i := range bids {
+		bid := &bids[i]
+		// Bids scrubbed after the end have neither a bidder nor a hidden commit anymore
+		if bid.Buyer == nil {
+			continue
+		}
+		bidder := certFingerprint(bid.Buyer)
+		if auction.AnonymousIDs {
+			bidder = hex.EncodeToString(anonymousID(auction.Name, bid.Buyer))
+		}
+		commitments = append(commitments, BidCommitment{
+			BidderFingerprint: bidder,
+			HiddenCommit:      hex.EncodeToString(bid.HiddenCommit),
+		})
+	}
+
+	return commitments, nil
+}
+
+// GetBiddingVelocity counts the bids of an open or closed auction per time bucket of the given length in seconds
+// The buckets are aligned to multiples of bucketSeconds and only buckets containing bids are returned, in ascending order.
+// Bids without a recorded timestamp are skipped. Auctions with HideBidCount do not disclose their bidding velocity.
+func (s *VickreyAuctionContract) GetBiddingVelocity(ctx contractapi.TransactionContextInterface, auctionName string, bucketSeconds int64) ([]BidBucket, error) {
+	if bucketSeconds <= 0 {
+		return nil, newAuctionError(ErrCodeInvalidArgument, "bucket length must be positive")
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	if auction.Status != AuctionStatus(Open) && auction.Status != AuctionStatus(Closed) {
+		return nil, newAuctionError(ErrCodeInvalidStatus, "bidding velocity is only available for open or closed auctions")
+	}
+	errBidCount := checkBidCountVisible(auction)
+	if errBidCount != nil {
+		return nil, errBidCount
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return nil, wrapError(errGetBids, "could not get the bids")
+	}
+
+	// Count the bids per bucket
+	bucketToNumBids := make(map[int64]int)
+	for i := range bids {
+		bid := &bids[i]
+		if bid.Timestamp == 0 {
+			continue
+		}
+		bucketStart := bid.Timestamp - bid.Timestamp%bucketSeconds
+		bucketToNumBids[bucketStart] += 1
+	}
+
+	// Convert map to a slice sorted by the bucket start
+	buckets := make([]BidBucket, 0, len(bucketToNumBids))
+	for bucketStart, numBids := range bucketToNumBids {
+		buckets = append(buckets, BidBucket{
+			Start:   bucketStart,
+			NumBids: numBids,
+		})
+	}
+	sort.Slice(buckets, func(i int, j int) bool {
+		return buckets[i].Start < buckets[j].Start
+	})
+
+	return buckets, nil
+}
+
+// CheckAuctionInvariants verifies the internal consistency of an auction and its bids for health checks
+// It returns the list of violated invariants, which is empty for a healthy auction.
+func (s *VickreyAuctionContract) CheckAuctionInvariants(ctx contractapi.TransactionContextInterface, auctionName string) ([]string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	// Get bids from world state
+	bids, errGetBids := getBids(ctx, auctionName)
+	if errGetBids != nil {
+		return nil, wrapError(errGetBids, "could not get the bids")
+	}
+
+	return auctionInvariantViolations(auction, bids), nil
+}
+
+// GetAuctionSeller returns the certificate of the auction seller in PEM format
+func (s *VickreyAuctionContract) GetAuctionSeller(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return "", fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	sellerCertPem := certDerToPem(auction.Seller)
+	if sellerCertPem == nil {
+		return "", fmt.Errorf("could not convert certificate from DER to PEM format")
+	}
+
+	return *sellerCertPem, nil
+}
+
+// AmISeller returns whether the submitting client is the seller of the auction
+// UIs can use it to decide whether to show the seller controls.
+func (s *VickreyAuctionContract) AmISeller(ctx contractapi.TransactionContextInterface, auctionName string) (bool, error) {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return false, fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return false, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return false, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	return reflect.DeepEqual(auction.Seller, clientID.Raw), nil
+}
+
+// GetAuctionFingerprints returns the certificate fingerprints of the seller and the winner of an auction
+// For auctions with more than one unit, the winner is the first winner, like in GetWinner.
+func (s *VickreyAuctionContract) GetAuctionFingerprints(ctx contractapi.TransactionContextInterface, auctionName string) (*AuctionFingerprints, error) {
+	// Get auction from world state
+	auction, errGetAuction := getAuction(ctx, auctionName)
+	if errGetAuction != nil {
+		return nil, fmt.Errorf("could not get the auction: %v", errGetAuction)
+	}
+	if auction == nil {
+		return nil, newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	fingerprints := &AuctionFingerprints{
+		Seller: certFingerprint(auction.Seller),
+	}
+	if auction.Status == AuctionStatus(Ended) {
+		result := auctionResult(auction)
+		if len(result.Winner) != 0 {
+			fingerprints.Winner = certFingerprint(result.Winner)
+		}
+	}
+	return fingerprints, nil
+}
+
+// GetBalance returns the balance of the submitting client, without the deposits locked in auctions
+func (s *VickreyAuctionContract) GetBalance(ctx contractapi.TransactionContextInterface) (uint64, error) {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return 0, fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	balance, errGetBalance := getBalance(ctx, certFingerprint(clientID.Raw))
+	if errGetBalance != nil {
+		return 0, fmt.Errorf("could not get the balance: %v", errGetBalance)
+	}
+
+	return balance, nil
+}
+
+// GetAuctionHistory returns all states of the auction, as JSON array of history entries
+// The entries are in the order in which the peer returns them, which is newest first since Fabric 2.0.
+// Bids are stored separately from the auction, so the summaries do not count the bidders.
+func (s *VickreyAuctionContract) GetAuctionHistory(ctx contractapi.TransactionContextInterface, auctionName string) (string, error) {
+	iterator, errHistory := ctx.GetStub().GetHistoryForKey(auctionKey(auctionName))
+	if errHistory != nil {
+		return "", fmt.Errorf("could not get the auction history: %v", errHistory)
+	}
+	defer iterator.Close()
+
+	history := []AuctionHistoryEntry{}
+	for iterator.HasNext() {
+		modification, errNext := iterator.Next()
+		if errNext != nil {
+			return "", fmt.Errorf("could not get the auction history: %v", errNext)
+		}
+		entry := AuctionHistoryEntry{
+			TxID:      modification.GetTxId(),
+			Timestamp: modification.GetTimestamp().GetSeconds(),
+			IsDelete:  modification.GetIsDelete(),
+			Summary:   nil,
+		}
+		if !entry.IsDelete {
+			var auction Auction
+			errUnmarshal := json.Unmarshal(modification.GetValue(), &auction)
+			if errUnmarshal != nil {
+				return "", fmt.Errorf("could not decode the auction of transaction %s: %v", entry.TxID, errUnmarshal)
+			}
+			entry.Summary = newAuctionSummary(&auction, nil)
+		}
+		history = append(history, entry)
+	}
+	if len(history) == 0 {
+		return "", newAuctionError(ErrCodeNotFound, "auction not found")
+	}
+
+	return marshalQueryResult(history)
+}
+
+// GetAuctionsBySeller returns the summaries of all auctions of the given PEM encoded seller certificate as JSON array
+func (s *VickreyAuctionContract) GetAuctionsBySeller(ctx contractapi.TransactionContextInterface, sellerPem string) (string, error) {
+	seller := certPemToDer(sellerPem)
+	if seller == nil {
+		return "", newAuctionError(ErrCodeInvalidArgument, "the certificate is not a valid PEM encoded X.509 certificate")
+	}
+
+	auctions, errGetAuctions := getAuctionsBySeller(ctx, seller)
+	if errGetAuctions != nil {
+		return "", fmt.Errorf("could not get the auctions: %v", errGetAuctions)
+	}
+
+	summaries := []*AuctionSummary{}
+	for _, auction := range auctions {
+		bids, errGetBids := getListingBids(ctx, auction)
+		if errGetBids != nil {
+			return "", wrapError(errGetBids, "could not get the bids")
+		}
+		summaries = append(summaries, newAuctionSummary(auction, bids))
+	}
+
+	return marshalQueryResult(summaries)
+}
+
+// QueryMyLeadingAuctions returns the summaries of the open English auctions in which the submitting client has the standing high bid as JSON array
+// Only English auctions have a standing high bid, in sealed and unsealed auctions the winners are determined when they end.
+func (s *VickreyAuctionContract) QueryMyLeadingAuctions(ctx contractapi.TransactionContextInterface) (string, error) {
+	// Get ID of submitting client
+	clientID, errClientID := getSubmittingClientIdentity(ctx)
+	if errClientID != nil {
+		return "", fmt.Errorf("failed to get client identity: %v", errClientID)
+	}
+
+	auctions, errGetAuctions := getAuctionsByHighBidder(ctx, clientID.Raw)
+	if errGetAuctions != nil {
+		return "", fmt.Errorf("could not get the auctions: %v", errGetAuctions)
+	}
+
+	summaries := []*AuctionSummary{}
+	for _, auction := range auctions {
+		if auction.Status != AuctionStatus(Open) {
+			continue
+		}
+		bids, errGetBids := getListingBids(ctx, auction)
+		if errGetBids != nil {
+			return "", wrapError(errGetBids, "could not get the bids")
+		}
+		summaries = append(summaries, newAuctionSummary(auction, bids))
+	}
+
+	return marshalQueryResult(summaries)
+}
+
+// GetAllAuctionSummaries returns the summaries of all auctions in the order of their names as JSON object
+// At most maxAuctionSummaries summaries are returned, the truncated flag tells whether auctions were left out.
+func (s *VickreyAuctionContract) GetAllAuctionSummaries(ctx contractapi.TransactionContextInterface) (string, error) {
+	auctions, truncated, errGetAuctions := getAuctions(ctx, maxAuctionSummaries)
+	if errGetAuctions != nil {
+		return "", fmt.Errorf("could not get the auctions: %v", errGetAuctions)
+	}
+
+	summaries := []*AuctionSummary{}
+	for _, auction := range auctions {
+		bids, errGetBids := getListingBids(ctx, auction)
+		if errGetBids != nil {
+			return "", wrapError(errGetBids, "could not get the bids")
+		}
+		summaries = append(summaries, newAuctionSummary(auction, bids))
+	}
+
+	return marshalQueryResult(AuctionSummaryList{
+		Summaries: summaries,
+		Truncated: truncated,
+	})
+}
+
+// GetOpenAuctions returns the summaries of all open auctions as JSON array
+func (s *VickreyAuctionContract) GetOpenAuctions(ctx contractapi.TransactionContextInterface) (string, error) {
+	auctions, errGetAuctions := getAllAuctions(ctx)
+	if errGetAuctions != nil {
+		return "", fmt.Errorf("could not get the auctions: %v", errGetAuctions)
+	}
+
+	summaries := []*AuctionSummary{}
+	for _, auction := range auctions {
+		if auction.Status != AuctionStatus(Open) {
+			continue
+		}
+		bids, errGetBids := getListingBids(ctx, auction)
+		if errGetBids != nil {
+			return "", wrapError(errGetBids, "could not get the bids")
+		}
+		summaries = append(summaries, newAuctionSummary(auction, bids))
+	}
+
+	return marshalQueryResult(summaries)
+}
@@ -0,0 +1,3348 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"golang.org/x/crypto/sha3"
+)
+
+// testBid is a bid which a test bidder submits and reveals later
+type testBid struct {
+	bidder *mockIdentity
+	price  uint64
+	salt   []byte
+}
+
+func newTestBid(t *testing.T, bidder *mockIdentity, price uint64) *testBid {
+	t.Helper()
+	salt := make([]byte, minSaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testBid{bidder: bidder, price: price, salt: salt}
+}
+
+func (bid *testBid) hiddenCommit(t *testing.T) string {
+	t.Helper()
+	hiddenCommit, err := hashBid(bid.bidder.cert, bid.price, bid.salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(hiddenCommit)
+}
+
+// signature signs the hidden commit of the bid with the key of the bidder like BidSigned expects
+func (bid *testBid) signature(t *testing.T, auctionName string) string {
+	t.Helper()
+	hiddenCommit, err := hex.DecodeString(bid.hiddenCommit(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(bidSignatureMessage(auctionName, hiddenCommit))
+	signature, err := ecdsa.SignASN1(rand.Reader, bid.bidder.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(signature)
+}
+
+// createAuctionWithPrice submits CreateAuction with the options encoded like the client application does
+func (network *testNetwork) createAuctionWithPrice(seller *mockIdentity, auctionName string, directBuyPrice uint64, options AuctionOptions) error {
+	network.t.Helper()
+	optionsJSON, err := json.Marshal(&options)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	_, err = network.submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CreateAuction(ctx, auctionName, directBuyPrice, string(optionsJSON))
+	})
+	return err
+}
+
+func (network *testNetwork) createAuction(seller *mockIdentity, auctionName string, options AuctionOptions) {
+	network.t.Helper()
+	err := network.createAuctionWithPrice(seller, auctionName, 0, options)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+}
+
+func (network *testNetwork) bid(auctionName string, bid *testBid) (*mockStub, error) {
+	hiddenCommit := bid.hiddenCommit(network.t)
+	return network.simulate(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.Bid(ctx, auctionName, hiddenCommit)
+	})
+}
+
+func (network *testNetwork) openBid(auctionName string, bid *testBid) {
+	network.t.Helper()
+	network.mustSubmit(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.OpenBid(ctx, auctionName, bid.price, hex.EncodeToString(bid.salt))
+	})
+}
+
+// placeBid submits an open bid of an English or unsealed auction
+func (network *testNetwork) placeBid(bidder *mockIdentity, auctionName string, price uint64) (*mockStub, error) {
+	return network.submit(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.PlaceBid(ctx, auctionName, price)
+	})
+}
+
+// endSealedAuction closes the auction, reveals the bids and ends it, it returns the summary of the end event
+func (network *testNetwork) endSealedAuction(seller *mockIdentity, auctionName string, bids []*testBid) *AuctionSummary {
+	network.t.Helper()
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, auctionName, 0)
+	})
+	for _, bid := range bids {
+		network.openBid(auctionName, bid)
+	}
+	stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, auctionName, 0)
+	})
+	var summary AuctionSummary
+	err := json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	return &summary
+}
+
+// mustBid submits a hidden bid which must succeed
+func (network *testNetwork) mustBid(auctionName string, bid *testBid) {
+	network.t.Helper()
+	stub, err := network.bid(auctionName, bid)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	err = network.ledger.commit(stub)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+}
+
+// auctionView evaluates GetAuctionJSON as the client and decodes the view
+func (network *testNetwork) auctionView(client *mockIdentity, auctionName string) *AuctionView {
+	network.t.Helper()
+	var viewJSON string
+	_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		viewJSON, err = network.contract.GetAuctionJSON(ctx, auctionName)
+		return err
+	})
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	var view AuctionView
+	err = json.Unmarshal([]byte(viewJSON), &view)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	return &view
+}
+
+// bidKeys returns the keys of all bids of the auction in the world state
+func (network *testNetwork) bidKeys(auctionName string) []string {
+	return network.ledger.rangeKeys("\x00bid\x00"+auctionName+"\x00", "\x00bid\x00"+auctionName+"\x00\U0010FFFF")
+}
+
+func TestNewChaincode(t *testing.T) {
+	// The contract API rejects the contract if its metadata does not match the schema, then the chaincode cannot start
+	auctionContract := new(VickreyAuctionContract)
+	auctionContract.TransactionContextHandler = new(AuctionTransactionContext)
+	_, err := contractapi.NewChaincode(auctionContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAuctionOptionsJSON(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+
+	tests := []struct {
+		name        string
+		optionsJSON string
+		wantCode    string
+	}{
+		{"no options", "", ""},
+		{"empty object", "{}", ""},
+		{"reserve", `{"hasReserve": true, "reservePrice": 50}`, ""},
+		{"invalid JSON", `{"hasReserve": `, ErrCodeInvalidArgument},
+		{"wrong type", `{"reservePrice": "50"}`, ErrCodeInvalidArgument},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auctionName := fmt.Sprintf("auction%d", i)
+			_, err := network.submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.CreateAuction(ctx, auctionName, 0, test.optionsJSON)
+			})
+			checkErrorCode(t, err, test.wantCode)
+		})
+	}
+
+	var auction Auction
+	err := json.Unmarshal(network.ledger.state[auctionKey("auction2")], &auction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auction.HasReserve || auction.ReservePrice != 50 {
+		t.Fatalf("the options were not applied: reserve %v %d", auction.HasReserve, auction.ReservePrice)
+	}
+}
+
+func TestEndAuctionOverBidKeys(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bids := []*testBid{
+		newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 10),
+		newTestBid(t, newMockIdentity(t, "bidder2", "Org2MSP"), 40),
+		newTestBid(t, newMockIdentity(t, "bidder3", "Org1MSP"), 20),
+	}
+
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	for _, bid := range bids {
+		stub, err := network.bid("auction1", bid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A bid only adds its own keys, it does not rewrite the auction
+		if _, rewritten := stub.writes[auctionKey("auction1")]; rewritten {
+			t.Fatal("bid rewrote the auction")
+		}
+		err = network.ledger.commit(stub)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if numKeys := len(network.bidKeys("auction1")); numKeys != len(bids) {
+		t.Fatalf("expected %d bid keys, got %d", len(bids), numKeys)
+	}
+	if strings.Contains(string(network.ledger.state[auctionKey("auction1")]), `"bids"`) {
+		t.Fatal("auction still stores bids inline")
+	}
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	for _, bid := range bids {
+		network.openBid("auction1", bid)
+	}
+	stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+
+	if stub.eventName != "auction auction1" {
+		t.Fatalf("unexpected event %q", stub.eventName)
+	}
+	var summary AuctionSummary
+	err := json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Status != AuctionStatus(Ended) || summary.NumBidders != len(bids) {
+		t.Fatalf("unexpected summary: status %v, %d bidders", summary.Status, summary.NumBidders)
+	}
+	if summary.Result == nil || !reflect.DeepEqual(summary.Result.Winner, bids[1].bidder.cert.Raw) {
+		t.Fatal("the highest bidder did not win")
+	}
+	if summary.Result.HammerPrice != 20 {
+		t.Fatalf("expected the second price 20, got %d", summary.Result.HammerPrice)
+	}
+}
+
+func TestConcurrentBids(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      AuctionOptions
+		wantConflict bool
+	}{
+		// The bid event counts the bids, which reads the key range of all bids
+		{"counted", AuctionOptions{}, true},
+		// Without the count, concurrent bids only read the unchanged auction
+		{"hidden count", AuctionOptions{HideBidCount: true}, false},
+		// The limit needs the count even if it is hidden
+		{"hidden count with limit", AuctionOptions{HideBidCount: true, MaxBids: 10}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+
+			// Both bids are endorsed on the same world state before either is committed
+			first, err := network.bid("auction1", newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := network.bid("auction1", newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 20))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = network.ledger.commit(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = network.ledger.commit(second)
+			if test.wantConflict {
+				if err == nil || !strings.HasPrefix(err.Error(), "PHANTOM_READ_CONFLICT") {
+					t.Fatalf("expected a phantom read conflict, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if numKeys := len(network.bidKeys("auction1")); numKeys != 2 {
+				t.Fatalf("expected 2 bid keys, got %d", numKeys)
+			}
+		})
+	}
+}
+
+func TestMigrateBids(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bids := []*testBid{
+		newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 30),
+		newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 50),
+	}
+
+	// Store an auction like an earlier version of the chaincode, with its bids inline
+	legacyAuction := Auction{
+		Name:    "legacy",
+		Seller:  seller.cert.Raw,
+		Status:  AuctionStatus(Open),
+		Version: 3,
+	}
+	for _, bid := range bids {
+		hiddenCommit, err := hex.DecodeString(bid.hiddenCommit(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacyAuction.LegacyBids = append(legacyAuction.LegacyBids, Bid{Buyer: bid.bidder.cert.Raw, HiddenCommit: hiddenCommit})
+	}
+	legacyAuctionJSON, err := json.Marshal(&legacyAuction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := network.ledger.newTransaction()
+	_ = setup.PutState(auctionKey("legacy"), legacyAuctionJSON)
+	err = network.ledger.commit(setup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The inline bids must not be ignored
+	_, err = network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetBidCount(ctx, "legacy")
+		return err
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrCodeInvalidStatus) {
+		t.Fatalf("expected %s before the migration, got %v", ErrCodeInvalidStatus, err)
+	}
+
+	_, err = network.submit(bids[0].bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.MigrateBids(ctx, "legacy")
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrCodePermissionDenied) {
+		t.Fatalf("expected %s for a bidder, got %v", ErrCodePermissionDenied, err)
+	}
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.MigrateBids(ctx, "legacy")
+	})
+	if numKeys := len(network.bidKeys("legacy")); numKeys != len(bids) {
+		t.Fatalf("expected %d bid keys, got %d", len(bids), numKeys)
+	}
+	if strings.Contains(string(network.ledger.state[auctionKey("legacy")]), `"bids"`) {
+		t.Fatal("auction still stores bids inline")
+	}
+
+	// The migrated bids can be revealed and win like new ones
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "legacy", 0)
+	})
+	for _, bid := range bids {
+		network.openBid("legacy", bid)
+	}
+	stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "legacy", 0)
+	})
+	var summary AuctionSummary
+	err = json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Result == nil || !reflect.DeepEqual(summary.Result.Winner, bids[1].bidder.cert.Raw) || summary.Result.HammerPrice != 30 {
+		t.Fatal("unexpected result of the migrated bids")
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	buyer := newMockIdentity(t, "buyer", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.SetDirectBuyPrice(ctx, "auction1", 100)
+	})
+
+	// The buyer read the auction before the direct buy price was set
+	_, err := network.submit(buyer, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction1", 100, 1)
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrCodeVersionConflict) {
+		t.Fatalf("expected %s for a stale version, got %v", ErrCodeVersionConflict, err)
+	}
+
+	// Transactions endorsed on the same version pass the check, the later one fails when it is committed
+	closeAuction, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	directBuy, err := network.simulate(buyer, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction1", 100, 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = network.ledger.commit(closeAuction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = network.ledger.commit(directBuy)
+	if err == nil || !strings.HasPrefix(err.Error(), "MVCC_READ_CONFLICT") {
+		t.Fatalf("expected an MVCC read conflict, got %v", err)
+	}
+
+	// Closing again is a no-op, whatever version the seller expected
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 2)
+	})
+}
+
+func TestCreateAuctionValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		directBuyPrice uint64
+		options        AuctionOptions
+		wantCode       string
+	}{
+		{"no options", 0, AuctionOptions{}, ""},
+		{"zero reserve", 0, AuctionOptions{HasReserve: true, ReservePrice: 0}, ""},
+		{"reserve within the bid range", 0, AuctionOptions{HasReserve: true, ReservePrice: 50, MaxBidPrice: 100}, ""},
+		{"reserve above the maximum bid price", 0, AuctionOptions{HasReserve: true, ReservePrice: 150, MaxBidPrice: 100}, ErrCodeInvalidArgument},
+		{"minimum above the maximum bid price", 0, AuctionOptions{MinBidPrice: 200, MaxBidPrice: 100}, ErrCodeInvalidArgument},
+		{"minimum above the direct buy price", 100, AuctionOptions{MinBidPrice: 200}, ErrCodeInvalidArgument},
+		{"short salt", 0, AuctionOptions{MinSaltBytes: minSaltLength - 1}, ErrCodeInvalidArgument},
+		{"unknown auction type", 0, AuctionOptions{AuctionType: "reverse"}, ErrCodeInvalidArgument},
+		{"multi-unit all-pay", 0, AuctionOptions{Quantity: 2, AuctionType: "allPay"}, ErrCodeInvalidArgument},
+		{"multi-unit direct buy", 100, AuctionOptions{Quantity: 2}, ErrCodeInvalidArgument},
+		{"Dutch without a clock", 0, AuctionOptions{AuctionType: "dutch"}, ErrCodeInvalidArgument},
+		{"clock of a sealed auction", 0, AuctionOptions{StartPrice: 100}, ErrCodeInvalidArgument},
+		{"increment of a sealed auction", 0, AuctionOptions{BidIncrement: 5}, ErrCodeInvalidArgument},
+		{"signatures of an unsealed auction", 0, AuctionOptions{Unsealed: true, RequireBidSignature: true}, ErrCodeInvalidArgument},
+		{"signatures of an English auction", 0, AuctionOptions{AuctionType: "english", RequireBidSignature: true}, ErrCodeInvalidArgument},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, fmt.Sprintf("auction%d", i), test.directBuyPrice, test.options)
+			checkErrorCode(t, err, test.wantCode)
+		})
+	}
+}
+
+func TestNoReserveVersusZeroReserve(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         AuctionOptions
+		bidPrices       []uint64
+		wantWinner      bool
+		wantHammerPrice uint64
+	}{
+		// Without a reserve, a sole bidder pays their own bid
+		{"no reserve", AuctionOptions{}, []uint64{30}, true, 30},
+		// A reserve of 0 acts like a competing bid of 0
+		{"zero reserve", AuctionOptions{HasReserve: true, ReservePrice: 0}, []uint64{30}, true, 0},
+		{"reserve met", AuctionOptions{HasReserve: true, ReservePrice: 20}, []uint64{30}, true, 20},
+		{"reserve not met", AuctionOptions{HasReserve: true, ReservePrice: 40}, []uint64{30}, false, 0},
+		// With competition, the reserve does not matter
+		{"zero reserve with two bidders", AuctionOptions{HasReserve: true, ReservePrice: 0}, []uint64{30, 10}, true, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+			bids := []*testBid{}
+			for _, price := range test.bidPrices {
+				bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), price)
+				network.mustBid("auction1", bid)
+				bids = append(bids, bid)
+			}
+
+			summary := network.endSealedAuction(seller, "auction1", bids)
+			if (summary.Result.Winner != nil) != test.wantWinner {
+				t.Fatalf("expected a winner: %v, got %v", test.wantWinner, summary.Result.Winner != nil)
+			}
+			if test.wantWinner && !reflect.DeepEqual(summary.Result.Winner, bids[0].bidder.cert.Raw) {
+				t.Fatal("the highest bidder did not win")
+			}
+			if summary.Result.HammerPrice != test.wantHammerPrice {
+				t.Fatalf("expected the hammer price %d, got %d", test.wantHammerPrice, summary.Result.HammerPrice)
+			}
+		})
+	}
+}
+
+func TestCancelAuction(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{ItemRef: "item1"})
+	network.createAuction(seller, "auction2", AuctionOptions{})
+	network.mustBid("auction2", newTestBid(t, bidder, 10))
+
+	cancel := func(client *mockIdentity, auctionName string) (*mockStub, error) {
+		return network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CancelAuction(ctx, auctionName)
+		})
+	}
+
+	_, err := cancel(bidder, "auction1")
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+	_, err = cancel(seller, "auction2")
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+	_, err = cancel(seller, "unknown")
+	checkErrorCode(t, err, ErrCodeNotFound)
+
+	stub, err := cancel(seller, "auction1")
+	checkErrorCode(t, err, "")
+	if _, exists := network.ledger.state[auctionKey("auction1")]; exists {
+		t.Fatal("the cancelled auction is still in the world state")
+	}
+	var summary AuctionSummary
+	err = json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.eventName != "auction auction1" || summary.Status != AuctionStatus(Cancelled) {
+		t.Fatalf("unexpected event %q with status %v", stub.eventName, summary.Status)
+	}
+
+	// The item can be sold again
+	network.createAuction(seller, "auction3", AuctionOptions{ItemRef: "item1"})
+}
+
+func TestHideBidCount(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	bidderPem := certPemString(bidder.cert.Raw)
+	network.createAuction(seller, "auction1", AuctionOptions{HideBidCount: true})
+	bid := newTestBid(t, bidder, 10)
+	network.mustBid("auction1", bid)
+
+	// query evaluates a query of the bidder and returns its error
+	query := func(query func(ctx contractapi.TransactionContextInterface) error) error {
+		_, err := network.simulate(bidder, query)
+		return err
+	}
+	// summaries evaluates a query returning summaries as JSON and returns their numbers of bidders
+	summaries := func(query func(ctx contractapi.TransactionContextInterface) (string, error)) []int {
+		t.Helper()
+		var resultJSON string
+		_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			resultJSON, err = query(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list AuctionSummaryList
+		if strings.HasPrefix(resultJSON, "[") {
+			err = json.Unmarshal([]byte(resultJSON), &list.Summaries)
+		} else {
+			err = json.Unmarshal([]byte(resultJSON), &list)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		numBidders := []int{}
+		for _, summary := range list.Summaries {
+			numBidders = append(numBidders, summary.NumBidders)
+		}
+		return numBidders
+	}
+
+	// Open auction
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetBidCount(ctx, "auction1")
+		return err
+	}), ErrCodePermissionDenied)
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetBiddingVelocity(ctx, "auction1", 60)
+		return err
+	}), ErrCodePermissionDenied)
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetRevealProgress(ctx, "auction1")
+		return err
+	}), ErrCodePermissionDenied)
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.HasBid(ctx, "auction1", bidderPem)
+		return err
+	}), ErrCodePermissionDenied)
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		viewJSON, err := network.contract.GetAuctionJSON(ctx, "auction1")
+		if err != nil {
+			return err
+		}
+		var view AuctionView
+		err = json.Unmarshal([]byte(viewJSON), &view)
+		if err != nil {
+			return err
+		}
+		if len(view.Bids) != 0 {
+			t.Fatalf("the view lists %d bids", len(view.Bids))
+		}
+		return nil
+	}), "")
+	for name, numBidders := range map[string][]int{
+		"GetOpenAuctions": summaries(func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return network.contract.GetOpenAuctions(ctx)
+		}),
+		"GetAllAuctionSummaries": summaries(func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return network.contract.GetAllAuctionSummaries(ctx)
+		}),
+		"GetAuctionsBySeller": summaries(func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return network.contract.GetAuctionsBySeller(ctx, certPemString(seller.cert.Raw))
+		}),
+	} {
+		if !reflect.DeepEqual(numBidders, []int{0}) {
+			t.Fatalf("%s disclosed the number of bidders: %v", name, numBidders)
+		}
+	}
+
+	// Closed auction
+	stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	var summary AuctionSummary
+	err := json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.NumBidders != 0 {
+		t.Fatalf("the close event disclosed %d bidders", summary.NumBidders)
+	}
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetCommitmentSet(ctx, "auction1")
+		return err
+	}), ErrCodePermissionDenied)
+
+	// Ended auction
+	network.openBid("auction1", bid)
+	stub = network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+	err = json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.NumBidders != 1 {
+		t.Fatalf("expected 1 bidder after the end, got %d", summary.NumBidders)
+	}
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		numBids, err := network.contract.GetBidCount(ctx, "auction1")
+		if err == nil && numBids != 1 {
+			t.Fatalf("expected 1 bid, got %d", numBids)
+		}
+		return err
+	}), "")
+	checkErrorCode(t, query(func(ctx contractapi.TransactionContextInterface) error {
+		hasBid, err := network.contract.HasBid(ctx, "auction1", bidderPem)
+		if err == nil && !hasBid {
+			t.Fatal("the bid was not found")
+		}
+		return err
+	}), "")
+}
+
+func TestAuctionEndorsementPolicy(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid := newTestBid(t, newMockIdentity(t, "bidder", "Org2MSP"), 10)
+	network.createAuction(seller, "auction1", AuctionOptions{})
+
+	setPolicy := func(client *mockIdentity, orgs []string, quorum uint32) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.SetAuctionEndorsementPolicy(ctx, "auction1", orgs, quorum)
+		})
+		return err
+	}
+	getPolicy := func() *EndorsementPolicy {
+		t.Helper()
+		var policy *EndorsementPolicy
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			policy, err = network.contract.GetAuctionEndorsementPolicy(ctx, "auction1")
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return policy
+	}
+
+	checkErrorCode(t, setPolicy(bid.bidder, []string{"Org1MSP"}, 0), ErrCodePermissionDenied)
+	checkErrorCode(t, setPolicy(seller, []string{"Org1MSP", "Org2MSP"}, 3), ErrCodeInvalidArgument)
+	checkErrorCode(t, setPolicy(seller, []string{"Org1MSP", ""}, 1), ErrCodeInvalidArgument)
+
+	// A quorum of 0 requires all orgs
+	checkErrorCode(t, setPolicy(seller, []string{"Org2MSP", "Org1MSP", "Org2MSP"}, 0), "")
+	if policy := getPolicy(); !reflect.DeepEqual(policy, &EndorsementPolicy{Orgs: []string{"Org1MSP", "Org2MSP"}, Quorum: 2}) {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+
+	checkErrorCode(t, setPolicy(seller, []string{"Org3MSP", "Org1MSP", "Org2MSP"}, 2), "")
+	wantPolicy := &EndorsementPolicy{Orgs: []string{"Org1MSP", "Org2MSP", "Org3MSP"}, Quorum: 2}
+	if policy := getPolicy(); !reflect.DeepEqual(policy, wantPolicy) {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+
+	// The policy is set on the auction key and copied to new bids
+	envelope := &common.SignaturePolicyEnvelope{}
+	err := proto.Unmarshal(network.ledger.policies[auctionKey("auction1")], envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := envelope.GetRule().GetNOutOf().GetN(); n != 2 || len(envelope.GetRule().GetNOutOf().GetRules()) != 3 || len(envelope.Identities) != 3 {
+		t.Fatalf("expected a 2 out of 3 policy, got %d out of %d", n, len(envelope.Identities))
+	}
+	network.mustBid("auction1", bid)
+	for _, key := range network.bidKeys("auction1") {
+		if !reflect.DeepEqual(network.ledger.policies[key], network.ledger.policies[auctionKey("auction1")]) {
+			t.Fatal("the bid does not have the policy of the auction")
+		}
+	}
+
+	// The ended auction records the policy
+	network.endSealedAuction(seller, "auction1", []*testBid{bid})
+	var auction Auction
+	err = json.Unmarshal(network.ledger.state[auctionKey("auction1")], &auction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(auction.EndorsingOrgs, wantPolicy.Orgs) || auction.EndorsementQuorum != 2 || auction.FinalizedBy != "Org1MSP" {
+		t.Fatalf("unexpected finalization: %v, quorum %d, by %s", auction.EndorsingOrgs, auction.EndorsementQuorum, auction.FinalizedBy)
+	}
+	checkErrorCode(t, setPolicy(seller, []string{"Org1MSP"}, 0), ErrCodeInvalidStatus)
+}
+
+func TestGetCommitmentSet(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bids := []*testBid{
+		newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 10),
+		newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 20),
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	for _, bid := range bids {
+		network.mustBid("auction1", bid)
+	}
+
+	getCommitmentSet := func() ([]BidCommitment, error) {
+		var commitments []BidCommitment
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			commitments, err = network.contract.GetCommitmentSet(ctx, "auction1")
+			return err
+		})
+		return commitments, err
+	}
+
+	_, err := getCommitmentSet()
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	commitments, err := getCommitmentSet()
+	checkErrorCode(t, err, "")
+	want := map[string]string{}
+	for _, bid := range bids {
+		want[certFingerprint(bid.bidder.cert.Raw)] = bid.hiddenCommit(t)
+	}
+	got := map[string]string{}
+	for _, commitment := range commitments {
+		got[commitment.BidderFingerprint] = commitment.HiddenCommit
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("the commitment set does not match the bids: %v", got)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	bid := newTestBid(t, bidder, 10)
+	network.createAuction(seller, "open", AuctionOptions{})
+	network.createAuction(seller, "closed", AuctionOptions{})
+	network.mustBid("closed", bid)
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "closed", 0)
+	})
+	network.openBid("closed", bid)
+
+	tests := []struct {
+		name        string
+		client      *mockIdentity
+		transaction func(ctx contractapi.TransactionContextInterface) error
+		wantCode    string
+	}{
+		{"create an existing auction", seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CreateAuction(ctx, "open", 0, "")
+		}, ErrCodeAlreadyExists},
+		{"create with an invalid name", seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CreateAuction(ctx, "", 0, "")
+		}, ErrCodeInvalidArgument},
+		{"close an unknown auction", seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CloseAuction(ctx, "unknown", 0)
+		}, ErrCodeNotFound},
+		{"close by a bidder", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CloseAuction(ctx, "open", 0)
+		}, ErrCodePermissionDenied},
+		{"end an open auction", seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.EndAuction(ctx, "open", 0)
+		}, ErrCodeInvalidStatus},
+		{"end by a bidder", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.EndAuction(ctx, "closed", 0)
+		}, ErrCodePermissionDenied},
+		{"bid on an unknown auction", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Bid(ctx, "unknown", bid.hiddenCommit(t))
+		}, ErrCodeNotFound},
+		{"bid on a closed auction", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Bid(ctx, "closed", bid.hiddenCommit(t))
+		}, ErrCodeInvalidStatus},
+		{"bid with an invalid commit", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Bid(ctx, "open", "abcd")
+		}, ErrCodeInvalidArgument},
+		{"bid by the seller", seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Bid(ctx, "open", bid.hiddenCommit(t))
+		}, ErrCodePermissionDenied},
+		{"reveal while open", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.OpenBid(ctx, "open", bid.price, hex.EncodeToString(bid.salt))
+		}, ErrCodeInvalidStatus},
+		{"reveal again", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.OpenBid(ctx, "closed", bid.price, hex.EncodeToString(bid.salt))
+		}, ErrCodeAlreadyRevealed},
+		{"reveal without a bid", newMockIdentity(t, "other", "Org1MSP"), func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.OpenBid(ctx, "closed", bid.price, hex.EncodeToString(bid.salt))
+		}, ErrCodeBidMismatch},
+		{"direct buy without a price", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.DirectBuy(ctx, "open", 100, 0)
+		}, ErrCodeInvalidStatus},
+		{"direct buy of an unknown auction", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.DirectBuy(ctx, "unknown", 100, 0)
+		}, ErrCodeNotFound},
+		{"status of an unknown auction", bidder, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := network.contract.GetAuctionStatus(ctx, "unknown")
+			return err
+		}, ErrCodeNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := network.simulate(test.client, test.transaction)
+			checkErrorCode(t, err, test.wantCode)
+		})
+	}
+}
+
+func TestInviteBidder(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	invited := newMockIdentity(t, "invited", "Org1MSP")
+	uninvited := newMockIdentity(t, "uninvited", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+
+	invite := func(client *mockIdentity, bidderPem string) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.InviteBidder(ctx, "auction1", bidderPem)
+		})
+		return err
+	}
+
+	// Without invitations, everyone can bid
+	network.mustBid("auction1", newTestBid(t, uninvited, 10))
+
+	checkErrorCode(t, invite(invited, certPemString(invited.cert.Raw)), ErrCodePermissionDenied)
+	checkErrorCode(t, invite(seller, "not a certificate"), ErrCodeInvalidArgument)
+	checkErrorCode(t, invite(seller, certPemString(invited.cert.Raw)), "")
+	// Inviting twice does not add the bidder again
+	checkErrorCode(t, invite(seller, certPemString(invited.cert.Raw)), "")
+	var auction Auction
+	err := json.Unmarshal(network.ledger.state[auctionKey("auction1")], &auction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auction.InvitedBidders) != 1 {
+		t.Fatalf("expected 1 invited bidder, got %d", len(auction.InvitedBidders))
+	}
+
+	network.mustBid("auction1", newTestBid(t, invited, 20))
+	_, err = network.bid("auction1", newTestBid(t, uninvited, 30))
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	checkErrorCode(t, invite(seller, certPemString(uninvited.cert.Raw)), ErrCodeInvalidStatus)
+}
+
+func TestMaxBids(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{MaxBids: 2})
+
+	network.mustBid("auction1", newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 10))
+	network.mustBid("auction1", newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 20))
+	_, err := network.bid("auction1", newTestBid(t, newMockIdentity(t, "bidder3", "Org1MSP"), 30))
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	// A withdrawn bid frees its place
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	network.createAuction(seller, "auction2", AuctionOptions{MaxBids: 1})
+	network.mustBid("auction2", newTestBid(t, bidder1, 10))
+	network.mustSubmit(bidder1, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.WithdrawBid(ctx, "auction2")
+	})
+	network.mustBid("auction2", newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 20))
+}
+
+func TestAnonymousIDs(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 10)
+	network.createAuction(seller, "auction1", AuctionOptions{AnonymousIDs: true})
+	stub, err := network.bid("auction1", bid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = network.ledger.commit(stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID is stable, but differs between auctions and does not contain the certificate
+	bidderID := anonymousID("auction1", bid.bidder.cert.Raw)
+	if !bytes.Equal(bidderID, anonymousID("auction1", bid.bidder.cert.Raw)) || len(bidderID) != anonymousIDLength {
+		t.Fatal("the anonymous ID is not stable")
+	}
+	if bytes.Equal(bidderID, anonymousID("auction2", bid.bidder.cert.Raw)) {
+		t.Fatal("the anonymous ID is the same in another auction")
+	}
+	var bidEvent BidEvent
+	err = json.Unmarshal(stub.eventPayload, &bidEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bidEvent.Buyer, bidderID) || bytes.Contains(stub.eventPayload, []byte(base64.StdEncoding.EncodeToString(bid.bidder.cert.Raw))) {
+		t.Fatal("the bid event does not carry the anonymous ID only")
+	}
+
+	summary := network.endSealedAuction(seller, "auction1", []*testBid{bid})
+	if !bytes.Equal(summary.Seller, anonymousID("auction1", seller.cert.Raw)) || !bytes.Equal(summary.Result.Winner, bidderID) {
+		t.Fatal("the summary does not carry the anonymous IDs")
+	}
+
+	// The stored auction keeps the certificates
+	var auction Auction
+	err = json.Unmarshal(network.ledger.state[auctionKey("auction1")], &auction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(auction.Seller, seller.cert.Raw) || !bytes.Equal(auction.Winner, bid.bidder.cert.Raw) {
+		t.Fatal("the stored auction lost the certificates")
+	}
+}
+
+func TestDirectBuyPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    uint64
+		wantCode string
+	}{
+		{"exact", 100, ""},
+		{"over", 250, ""},
+		{"under", 99, ErrCodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			buyer := newMockIdentity(t, "buyer", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, "auction1", 100, AuctionOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stub, err := network.submit(buyer, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.DirectBuy(ctx, "auction1", test.price, 0)
+			})
+			checkErrorCode(t, err, test.wantCode)
+			if test.wantCode != "" {
+				return
+			}
+			// Offering more than the direct buy price does not make the buyer pay more
+			var summary AuctionSummary
+			err = json.Unmarshal(stub.eventPayload, &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Status != Ended || !summary.Result.DirectBuy || summary.Result.HammerPrice != 100 {
+				t.Fatalf("unexpected result: status %v, hammer price %d", summary.Status, summary.Result.HammerPrice)
+			}
+		})
+	}
+}
+
+func TestSummarySchemaVersion(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	buyer := newMockIdentity(t, "buyer", "Org1MSP")
+	bid := newTestBid(t, buyer, 10)
+
+	stubs := []*mockStub{}
+	submit := func(client *mockIdentity, transaction func(ctx contractapi.TransactionContextInterface) error) {
+		t.Helper()
+		stubs = append(stubs, network.mustSubmit(client, transaction))
+	}
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CreateAuction(ctx, "auction1", 0, "")
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.SetDirectBuyPrice(ctx, "auction1", 100)
+	})
+	network.mustBid("auction1", bid)
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.ReopenAuction(ctx, "auction1")
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	network.openBid("auction1", bid)
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.InitLedger(ctx)
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.PurgeEndedAuction(ctx, "auction1")
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CreateAuction(ctx, "auction2", 100, "")
+	})
+	submit(buyer, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction2", 100, 0)
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CreateAuction(ctx, "auction3", 0, "")
+	})
+	submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CancelAuction(ctx, "auction3")
+	})
+
+	numEvents := 0
+	for _, stub := range stubs {
+		if stub.eventName == "" {
+			continue
+		}
+		numEvents += 1
+		var summary AuctionSummary
+		err := json.Unmarshal(stub.eventPayload, &summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.SchemaVersion != auctionSummarySchemaVersion {
+			t.Fatalf("event %q has schema version %d", stub.eventName, summary.SchemaVersion)
+		}
+	}
+	if numEvents < 8 {
+		t.Fatalf("expected at least 8 summary events, got %d", numEvents)
+	}
+}
+
+func TestItemInformationLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  AuctionOptions
+		wantCode string
+	}{
+		{"description at the limit", AuctionOptions{Description: strings.Repeat("a", maxDescriptionLength)}, ""},
+		{"description too long", AuctionOptions{Description: strings.Repeat("a", maxDescriptionLength+1)}, ErrCodeInvalidArgument},
+		{"metadata URI at the limit", AuctionOptions{MetadataURI: strings.Repeat("a", maxMetadataURILength)}, ""},
+		{"metadata URI too long", AuctionOptions{MetadataURI: strings.Repeat("a", maxMetadataURILength+1)}, ErrCodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, "auction1", 0, test.options)
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil {
+				return
+			}
+
+			view := network.auctionView(seller, "auction1")
+			if view.Description != test.options.Description || view.MetadataURI != test.options.MetadataURI {
+				t.Fatal("the auction does not contain the item information")
+			}
+		})
+	}
+}
+
+func TestGetAllAuctionSummariesTruncated(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+
+	getSummaries := func() AuctionSummaryList {
+		t.Helper()
+		var listJSON string
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			listJSON, err = network.contract.GetAllAuctionSummaries(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list AuctionSummaryList
+		err = json.Unmarshal([]byte(listJSON), &list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	// Exactly as many auctions as summaries can be returned are not truncated
+	for i := 0; i < maxAuctionSummaries; i++ {
+		network.createAuction(seller, fmt.Sprintf("auction%03d", i), AuctionOptions{})
+	}
+	list := getSummaries()
+	if len(list.Summaries) != maxAuctionSummaries || list.Truncated {
+		t.Fatalf("expected %d summaries without truncation, got %d, truncated: %v", maxAuctionSummaries, len(list.Summaries), list.Truncated)
+	}
+
+	// One more auction is left out
+	network.createAuction(seller, fmt.Sprintf("auction%03d", maxAuctionSummaries), AuctionOptions{})
+	list = getSummaries()
+	if len(list.Summaries) != maxAuctionSummaries || !list.Truncated {
+		t.Fatalf("expected %d summaries with truncation, got %d, truncated: %v", maxAuctionSummaries, len(list.Summaries), list.Truncated)
+	}
+	if list.Summaries[0].Name != "auction000" || list.Summaries[maxAuctionSummaries-1].Name != fmt.Sprintf("auction%03d", maxAuctionSummaries-1) {
+		t.Fatal("the summaries are not the first auctions in the order of their names")
+	}
+}
+
+func TestSoleBidderPaysOwnBid(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         AuctionOptions
+		bidPrices       []uint64
+		wantHammerPrice uint64
+	}{
+		{"sole bidder pays the reserve", AuctionOptions{HasReserve: true, ReservePrice: 20}, []uint64{30}, 20},
+		{"sole bidder pays their own bid", AuctionOptions{HasReserve: true, ReservePrice: 20, SoleBidderPaysOwnBid: true}, []uint64{30}, 30},
+		// A bidder below the reserve is not a competitor
+		{"sole eligible bidder pays their own bid", AuctionOptions{HasReserve: true, ReservePrice: 20, SoleBidderPaysOwnBid: true}, []uint64{30, 10}, 30},
+		// With competition, the option does not matter
+		{"competing bidders", AuctionOptions{HasReserve: true, ReservePrice: 20, SoleBidderPaysOwnBid: true}, []uint64{30, 25}, 25},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+			bids := []*testBid{}
+			for _, price := range test.bidPrices {
+				bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), price)
+				network.mustBid("auction1", bid)
+				bids = append(bids, bid)
+			}
+
+			summary := network.endSealedAuction(seller, "auction1", bids)
+			if !reflect.DeepEqual(summary.Result.Winner, bids[0].bidder.cert.Raw) {
+				t.Fatal("the highest bidder did not win")
+			}
+			if summary.Result.HammerPrice != test.wantHammerPrice {
+				t.Fatalf("expected the hammer price %d, got %d", test.wantHammerPrice, summary.Result.HammerPrice)
+			}
+		})
+	}
+}
+
+func TestUnsealedAuction(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{Unsealed: true})
+
+	// Unsealed auctions take no hidden commits
+	_, err := network.bid("auction1", newTestBid(t, bidder1, 10))
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	// Open bids are published with their price
+	stub, err := network.placeBid(bidder1, "auction1", 30)
+	checkErrorCode(t, err, "")
+	var bidEvent BidEvent
+	err = json.Unmarshal(stub.eventPayload, &bidEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bidEvent.Price != 30 || bidEvent.NumBids != 1 {
+		t.Fatalf("expected an event of the first bid with the price 30, got %+v", bidEvent)
+	}
+
+	// Unlike in an English auction, lower bids are accepted
+	_, err = network.placeBid(bidder2, "auction1", 20)
+	checkErrorCode(t, err, "")
+	_, err = network.placeBid(seller, "auction1", 40)
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+
+	// The auction ends without any reveals, the highest bidder pays the second highest bid
+	summary := network.endSealedAuction(seller, "auction1", nil)
+	if !reflect.DeepEqual(summary.Result.Winner, bidder1.cert.Raw) {
+		t.Fatal("the highest bidder did not win")
+	}
+	if summary.Result.HammerPrice != 20 {
+		t.Fatalf("expected the hammer price 20, got %d", summary.Result.HammerPrice)
+	}
+
+	// No more bids after the end
+	_, err = network.placeBid(bidder2, "auction1", 50)
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	// Sealed auctions take no open bids
+	network.createAuction(seller, "auction2", AuctionOptions{})
+	_, err = network.placeBid(bidder1, "auction2", 30)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+}
+
+func TestRevealEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    AuctionOptions
+		wantPrices []uint64
+	}{
+		{"prices published", AuctionOptions{}, []uint64{30}},
+		{"prices redacted", AuctionOptions{RedactRevealedPrices: true}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+			bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 30)
+			network.mustBid("auction1", bid)
+			network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.CloseAuction(ctx, "auction1", 0)
+			})
+
+			stub := network.mustSubmit(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.OpenBid(ctx, "auction1", bid.price, hex.EncodeToString(bid.salt))
+			})
+			if stub.eventName != revealEventName("auction1") {
+				t.Fatalf("expected the reveal event, got %q", stub.eventName)
+			}
+			var revealEvent RevealEvent
+			err := json.Unmarshal(stub.eventPayload, &revealEvent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revealEvent.AuctionName != "auction1" || !reflect.DeepEqual(revealEvent.Buyer, bid.bidder.cert.Raw) {
+				t.Fatalf("the reveal event names the wrong auction or buyer: %+v", revealEvent)
+			}
+			if revealEvent.NumRevealed != 1 {
+				t.Fatalf("expected 1 revealed bid, got %d", revealEvent.NumRevealed)
+			}
+			if !reflect.DeepEqual(revealEvent.Prices, test.wantPrices) {
+				t.Fatalf("expected the prices %v, got %v", test.wantPrices, revealEvent.Prices)
+			}
+
+			// Redacted prices must not appear in the raw payload either
+			if test.options.RedactRevealedPrices && bytes.Contains(stub.eventPayload, []byte("prices")) {
+				t.Fatalf("the payload contains the prices: %s", stub.eventPayload)
+			}
+		})
+	}
+}
+
+func TestStrikeBidder(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	abuser := newMockIdentity(t, "abuser", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	strike := func(client *mockIdentity, auctionName string, bidder *mockIdentity) (*mockStub, error) {
+		return network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.StrikeBidder(ctx, auctionName, certPemString(bidder.cert.Raw))
+		})
+	}
+
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	network.mustBid("auction1", newTestBid(t, abuser, 10))
+	network.mustBid("auction1", newTestBid(t, abuser, 20))
+	network.mustBid("auction1", newTestBid(t, bidder, 30))
+
+	// Only the seller may strike bidders
+	_, err := strike(bidder, "auction1", abuser)
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+	if len(network.bidKeys("auction1")) != 3 {
+		t.Fatal("an unauthorized strike removed bids")
+	}
+
+	stub, err := strike(seller, "auction1", abuser)
+	checkErrorCode(t, err, "")
+	if len(network.bidKeys("auction1")) != 1 {
+		t.Fatalf("expected only the bid of the other bidder to remain, got %d bids", len(network.bidKeys("auction1")))
+	}
+	var strikeEvent StrikeEvent
+	err = json.Unmarshal(stub.eventPayload, &strikeEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(strikeEvent.Buyer, abuser.cert.Raw) || strikeEvent.NumStruck != 2 {
+		t.Fatalf("expected an event of 2 struck bids of the abuser, got %+v", strikeEvent)
+	}
+
+	// Striking again finds nothing
+	_, err = strike(seller, "auction1", abuser)
+	checkErrorCode(t, err, ErrCodeNotFound)
+
+	// The open bids of an unsealed auction are revealed, so they are kept
+	network.createAuction(seller, "auction2", AuctionOptions{Unsealed: true})
+	_, err = network.placeBid(abuser, "auction2", 10)
+	checkErrorCode(t, err, "")
+	_, err = strike(seller, "auction2", abuser)
+	checkErrorCode(t, err, ErrCodeNotFound)
+	if len(network.bidKeys("auction2")) != 1 {
+		t.Fatal("the revealed bid was struck")
+	}
+
+	// Once the auction is closed, no bids can be struck
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	_, err = strike(seller, "auction1", bidder)
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+}
+
+func TestGetAuctionJSONHidesCommitments(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 30)
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	network.mustBid("auction1", bid)
+
+	// While the auction is open, the hidden commit is not even part of the raw JSON
+	var viewJSON string
+	_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		viewJSON, err = network.contract.GetAuctionJSON(ctx, "auction1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(viewJSON, "hiddenCommit") || strings.Contains(viewJSON, bid.hiddenCommit(t)) {
+		t.Fatalf("the view of the open auction contains the hidden commit: %s", viewJSON)
+	}
+	view := network.auctionView(seller, "auction1")
+	if view.SellerPem != certPemString(seller.cert.Raw) || view.Status != AuctionStatus(Open) {
+		t.Fatalf("expected the PEM of the seller and the open status, got %q and %q", view.SellerPem, view.Status)
+	}
+	if len(view.Bids) != 1 || view.Bids[0].BidderFingerprint != certFingerprint(bid.bidder.cert.Raw) {
+		t.Fatalf("expected the bid with the fingerprint of the bidder, got %+v", view.Bids)
+	}
+
+	// Once closed, the bidders reveal against the published commits
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	view = network.auctionView(seller, "auction1")
+	if view.Status != AuctionStatus(Closed) || len(view.Bids) != 1 || view.Bids[0].HiddenCommit != bid.hiddenCommit(t) {
+		t.Fatalf("expected the hidden commit of the closed auction, got %+v", view.Bids)
+	}
+}
+
+func TestSetDirectBuyPrice(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	buyer := newMockIdentity(t, "buyer", "Org1MSP")
+	setPrice := func(client *mockIdentity, price uint64) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.SetDirectBuyPrice(ctx, "auction1", price)
+		})
+		return err
+	}
+	directBuy := func(price uint64) error {
+		_, err := network.simulate(buyer, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.DirectBuy(ctx, "auction1", price, 0)
+		})
+		return err
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{HasReserve: true, ReservePrice: 50})
+
+	// Only the seller may set the price
+	checkErrorCode(t, setPrice(buyer, 100), ErrCodePermissionDenied)
+	checkErrorCode(t, directBuy(100), ErrCodeInvalidStatus)
+
+	// The price cannot undercut the reserve
+	checkErrorCode(t, setPrice(seller, 40), ErrCodeInvalidArgument)
+
+	checkErrorCode(t, setPrice(seller, 100), "")
+	if network.auctionView(seller, "auction1").DirectBuyPrice != 100 {
+		t.Fatal("the direct buy price was not set")
+	}
+	checkErrorCode(t, directBuy(100), "")
+
+	// A price of 0 disables direct buy again
+	checkErrorCode(t, setPrice(seller, 0), "")
+	checkErrorCode(t, directBuy(100), ErrCodeInvalidStatus)
+
+	// The price is fixed once the auction is closed
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	checkErrorCode(t, setPrice(seller, 100), ErrCodeInvalidStatus)
+}
+
+func TestAuctionCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		wantCode string
+	}{
+		{"unspecified", "", ""},
+		{"fiat", "USD", ""},
+		{"tokens", "tokens", ""},
+		{"wrong case", "usd", ErrCodeInvalidArgument},
+		{"unsupported", "BTC", ErrCodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, "auction1", 0, AuctionOptions{Currency: test.currency})
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil {
+				return
+			}
+
+			// The result carries the currency, so the payment can be settled off-chain
+			bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 30)
+			network.mustBid("auction1", bid)
+			summary := network.endSealedAuction(seller, "auction1", []*testBid{bid})
+			if summary.Result.Currency != test.currency {
+				t.Fatalf("expected the currency %q in the result, got %q", test.currency, summary.Result.Currency)
+			}
+		})
+	}
+}
+
+func TestCreateAuctionName(t *testing.T) {
+	tests := []struct {
+		name        string
+		auctionName string
+		wantCode    string
+	}{
+		{"maximum length", strings.Repeat("a", defaultMaxAuctionNameLength), ""},
+		{"too long", strings.Repeat("a", defaultMaxAuctionNameLength+1), ErrCodeInvalidArgument},
+		{"composite key delimiter", "auction\x00name", ErrCodeInvalidArgument},
+		{"control character", "auction\n", ErrCodeInvalidArgument},
+		{"empty", "", ErrCodeInvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, test.auctionName, 0, AuctionOptions{})
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil && len(network.ledger.state) != 0 {
+				t.Fatal("a rejected auction was written to the world state")
+			}
+		})
+	}
+}
+
+func TestAmISeller(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	// A client with the same name but another certificate is not the seller
+	impostor := newMockIdentity(t, "seller", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	amISeller := func(client *mockIdentity, auctionName string) (bool, error) {
+		var isSeller bool
+		_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			isSeller, err = network.contract.AmISeller(ctx, auctionName)
+			return err
+		})
+		return isSeller, err
+	}
+
+	isSeller, err := amISeller(seller, "auction1")
+	checkErrorCode(t, err, "")
+	if !isSeller {
+		t.Fatal("the seller is not recognized")
+	}
+	isSeller, err = amISeller(impostor, "auction1")
+	checkErrorCode(t, err, "")
+	if isSeller {
+		t.Fatal("another client is recognized as the seller")
+	}
+	_, err = amISeller(seller, "auction2")
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestEndAuctionBidBook(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid1 := newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 20)
+	bid2 := newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 30)
+	bid3 := newTestBid(t, newMockIdentity(t, "bidder3", "Org1MSP"), 10)
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	for _, bid := range []*testBid{bid1, bid2, bid3} {
+		network.mustBid("auction1", bid)
+	}
+
+	// The bids are listed by descending price with the anonymous IDs of the bidders
+	summary := network.endSealedAuction(seller, "auction1", []*testBid{bid1, bid2, bid3})
+	wantBidBook := []BidBookEntry{
+		{BuyerHash: anonymousID("auction1", bid2.bidder.cert.Raw), BidPrice: 30},
+		{BuyerHash: anonymousID("auction1", bid1.bidder.cert.Raw), BidPrice: 20},
+		{BuyerHash: anonymousID("auction1", bid3.bidder.cert.Raw), BidPrice: 10},
+	}
+	if !reflect.DeepEqual(summary.BidBook, wantBidBook) {
+		t.Fatalf("expected the bid book %+v, got %+v", wantBidBook, summary.BidBook)
+	}
+	// The second highest price in the bid book is the hammer price
+	if summary.Result.HammerPrice != summary.BidBook[1].BidPrice {
+		t.Fatalf("the hammer price %d does not follow from the bid book", summary.Result.HammerPrice)
+	}
+
+	// Auctions which redact the revealed prices publish no bid book
+	network.createAuction(seller, "auction2", AuctionOptions{RedactRevealedPrices: true})
+	bid := newTestBid(t, bid1.bidder, 20)
+	network.mustBid("auction2", bid)
+	summary = network.endSealedAuction(seller, "auction2", []*testBid{bid})
+	if summary.BidBook != nil {
+		t.Fatalf("expected no bid book, got %+v", summary.BidBook)
+	}
+}
+
+func TestCommitDifficulty(t *testing.T) {
+	const difficulty = 8
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{CommitDifficulty: difficulty})
+
+	// newDifficultyBid grinds through salts until the commit has enough leading zero bits, or not
+	newDifficultyBid := func(satisfied bool) *testBid {
+		t.Helper()
+		for {
+			bid := newTestBid(t, bidder, 30)
+			hiddenCommit, err := hex.DecodeString(bid.hiddenCommit(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (leadingZeroBits(hiddenCommit) >= difficulty) == satisfied {
+				return bid
+			}
+		}
+	}
+
+	_, err := network.bid("auction1", newDifficultyBid(false))
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+
+	bid := newDifficultyBid(true)
+	network.mustBid("auction1", bid)
+	summary := network.endSealedAuction(seller, "auction1", []*testBid{bid})
+	if !reflect.DeepEqual(summary.Result.Winner, bidder.cert.Raw) {
+		t.Fatal("the bid with a sufficient commit did not win")
+	}
+
+	// The difficulty is limited and only applies to sealed bids
+	err = network.createAuctionWithPrice(seller, "auction2", 0, AuctionOptions{CommitDifficulty: maxCommitDifficulty + 1})
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+	err = network.createAuctionWithPrice(seller, "auction2", 0, AuctionOptions{CommitDifficulty: difficulty, Unsealed: true})
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+}
+
+func TestGetAuctionFingerprints(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 30)
+	getFingerprints := func(auctionName string) *AuctionFingerprints {
+		t.Helper()
+		var fingerprints *AuctionFingerprints
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			fingerprints, err = network.contract.GetAuctionFingerprints(ctx, auctionName)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fingerprints
+	}
+	// The fingerprint is the SHA-256 hash of the DER encoded certificate
+	fingerprint := func(client *mockIdentity) string {
+		hash := sha256.Sum256(client.cert.Raw)
+		return hex.EncodeToString(hash[:])
+	}
+
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	network.mustBid("auction1", bid)
+	fingerprints := getFingerprints("auction1")
+	if fingerprints.Seller != fingerprint(seller) || fingerprints.Winner != "" {
+		t.Fatalf("expected only the fingerprint of the seller while open, got %+v", fingerprints)
+	}
+
+	network.endSealedAuction(seller, "auction1", []*testBid{bid})
+	fingerprints = getFingerprints("auction1")
+	if fingerprints.Seller != fingerprint(seller) || fingerprints.Winner != fingerprint(bid.bidder) {
+		t.Fatalf("expected the fingerprints of the seller and the winner, got %+v", fingerprints)
+	}
+
+	// An auction without bids ends without a winner
+	network.createAuction(seller, "auction2", AuctionOptions{})
+	network.endSealedAuction(seller, "auction2", nil)
+	fingerprints = getFingerprints("auction2")
+	if fingerprints.Seller != fingerprint(seller) || fingerprints.Winner != "" {
+		t.Fatalf("expected no winner fingerprint, got %+v", fingerprints)
+	}
+}
+
+func TestEnglishAuction(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{AuctionType: "english", MinBidPrice: 10, BidIncrement: 5})
+
+	// The first bid must meet the minimum, every further bid must outbid the high bid by the increment
+	_, err := network.placeBid(bidder1, "auction1", 9)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+	_, err = network.placeBid(bidder1, "auction1", 10)
+	checkErrorCode(t, err, "")
+	_, err = network.placeBid(bidder2, "auction1", 14)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+	_, err = network.placeBid(bidder2, "auction1", 15)
+	checkErrorCode(t, err, "")
+	_, err = network.placeBid(bidder1, "auction1", 15)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+	_, err = network.placeBid(bidder1, "auction1", 25)
+	checkErrorCode(t, err, "")
+
+	// The hammer price follows the high bid while the auction is open
+	view := network.auctionView(seller, "auction1")
+	if view.HammerPrice != 25 {
+		t.Fatalf("expected the standing high bid 25, got %d", view.HammerPrice)
+	}
+
+	// The standing high bidder wins and pays their bid
+	summary := network.endSealedAuction(seller, "auction1", nil)
+	if !reflect.DeepEqual(summary.Result.Winner, bidder1.cert.Raw) || summary.Result.HammerPrice != 25 {
+		t.Fatalf("expected bidder1 to win at 25, got the hammer price %d", summary.Result.HammerPrice)
+	}
+}
+
+func TestPlaceBidMaxBids(t *testing.T) {
+	for _, options := range []AuctionOptions{
+		{AuctionType: "english", MaxBids: 2},
+		{Unsealed: true, MaxBids: 2},
+		{Unsealed: true, MaxBids: 2, HideBidCount: true},
+	} {
+		network := newTestNetwork(t)
+		seller := newMockIdentity(t, "seller", "Org1MSP")
+		bidder := newMockIdentity(t, "bidder", "Org1MSP")
+		network.createAuction(seller, "auction1", options)
+
+		for i, price := range []uint64{10, 20, 30} {
+			wantCode := ""
+			if i >= int(options.MaxBids) {
+				wantCode = ErrCodeInvalidStatus
+			}
+			_, err := network.placeBid(bidder, "auction1", price)
+			checkErrorCode(t, err, wantCode)
+		}
+		if len(network.bidKeys("auction1")) != int(options.MaxBids) {
+			t.Fatalf("expected %d bids, got %d", options.MaxBids, len(network.bidKeys("auction1")))
+		}
+	}
+}
+
+func TestBidSignature(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	bidSigned := func(auctionName string, bid *testBid, signatureHex string) error {
+		_, err := network.submit(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.BidSigned(ctx, auctionName, bid.hiddenCommit(t), signatureHex)
+		})
+		return err
+	}
+	replaceBid := func(auctionName string, bid *testBid) error {
+		_, err := network.submit(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.ReplaceBid(ctx, auctionName, bid.hiddenCommit(t))
+		})
+		return err
+	}
+	storedBid := func(auctionName string) *Bid {
+		t.Helper()
+		keys := network.bidKeys(auctionName)
+		if len(keys) != 1 {
+			t.Fatalf("expected a single bid, got %d", len(keys))
+		}
+		var bid Bid
+		err := json.Unmarshal(network.ledger.state[keys[0]], &bid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &bid
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{RequireBidSignature: true})
+
+	// Unsigned bids and tampered signatures are rejected
+	bid := newTestBid(t, bidder, 30)
+	_, err := network.bid("auction1", bid)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+	signature, err := hex.DecodeString(bid.signature(t, "auction1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature[len(signature)-1] ^= 0x01
+	checkErrorCode(t, bidSigned("auction1", bid, hex.EncodeToString(signature)), ErrCodeInvalidArgument)
+	// A signature for another auction does not verify either
+	checkErrorCode(t, bidSigned("auction1", bid, bid.signature(t, "auction2")), ErrCodeInvalidArgument)
+
+	checkErrorCode(t, bidSigned("auction1", bid, bid.signature(t, "auction1")), "")
+	signedBid := storedBid("auction1")
+	err = verifyBidSignature(bidder.cert, "auction1", signedBid.HiddenCommit, signedBid.Signature)
+	if err != nil {
+		t.Fatalf("the stored signature does not verify: %v", err)
+	}
+
+	// Signed bids of an auction which requires them cannot be replaced
+	checkErrorCode(t, replaceBid("auction1", newTestBid(t, bidder, 40)), ErrCodeInvalidStatus)
+
+	// Elsewhere, replacing a signed bid drops the signature of the old commit
+	network.createAuction(seller, "auction2", AuctionOptions{})
+	bid = newTestBid(t, bidder, 30)
+	checkErrorCode(t, bidSigned("auction2", bid, bid.signature(t, "auction2")), "")
+	if storedBid("auction2").Signature == nil {
+		t.Fatal("the signature was not stored")
+	}
+	replacement := newTestBid(t, bidder, 40)
+	checkErrorCode(t, replaceBid("auction2", replacement), "")
+	replacedBid := storedBid("auction2")
+	if hex.EncodeToString(replacedBid.HiddenCommit) != replacement.hiddenCommit(t) || replacedBid.Signature != nil {
+		t.Fatal("the replaced bid kept the signature of the old commit")
+	}
+}
+
+func TestScrubLosersAfterEnd(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	winner := newTestBid(t, newMockIdentity(t, "winner", "Org1MSP"), 30)
+	loser := newTestBid(t, newMockIdentity(t, "loser", "Org1MSP"), 20)
+	network.createAuction(seller, "auction1", AuctionOptions{ScrubLosersAfterEnd: true})
+	network.mustBid("auction1", winner)
+	network.mustBid("auction1", loser)
+	commitIndexKeys := func() []string {
+		return network.ledger.rangeKeys("\x00bidCommit\x00auction1\x00", "\x00bidCommit\x00auction1\x00\U0010FFFF")
+	}
+	if len(commitIndexKeys()) != 2 {
+		t.Fatalf("expected an index entry per bid, got %d", len(commitIndexKeys()))
+	}
+
+	network.endSealedAuction(seller, "auction1", []*testBid{winner, loser})
+
+	// The losing bid only keeps its price, and its hidden commit is not left in the index
+	if keys := commitIndexKeys(); len(keys) != 0 {
+		t.Fatalf("expected no hidden commit index entries, got %q", keys)
+	}
+	for _, key := range network.bidKeys("auction1") {
+		var bid Bid
+		err := json.Unmarshal(network.ledger.state[key], &bid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bid.BidPrice == loser.price && (bid.Buyer != nil || bid.HiddenCommit != nil) {
+			t.Fatal("the losing bid was not scrubbed")
+		}
+		if bid.BidPrice == winner.price && !reflect.DeepEqual(bid.Buyer, winner.bidder.cert.Raw) {
+			t.Fatal("the winning bid was scrubbed")
+		}
+	}
+	for key := range network.ledger.state {
+		if strings.Contains(key, loser.hiddenCommit(t)) {
+			t.Fatalf("the key %q contains the hidden commit of the losing bid", key)
+		}
+	}
+}
+
+func TestSetMaxAuctionNameLength(t *testing.T) {
+	network := newTestNetwork(t)
+	admin := newMockIdentity(t, "admin", "Org1MSP")
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	setMaxLength := func(client *mockIdentity, maxLength uint32) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.SetMaxAuctionNameLength(ctx, maxLength)
+		})
+		return err
+	}
+	getMaxLength := func() uint32 {
+		t.Helper()
+		var maxLength uint32
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			maxLength, err = network.contract.GetMaxAuctionNameLength(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return maxLength
+	}
+	network.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.InitLedger(ctx)
+	})
+	if getMaxLength() != defaultMaxAuctionNameLength {
+		t.Fatalf("expected the default limit %d, got %d", defaultMaxAuctionNameLength, getMaxLength())
+	}
+
+	// Only the admin can change the limit, within the upper bound
+	checkErrorCode(t, setMaxLength(seller, 256), ErrCodePermissionDenied)
+	checkErrorCode(t, setMaxLength(admin, upperMaxAuctionNameLength+1), ErrCodeInvalidArgument)
+
+	checkErrorCode(t, setMaxLength(admin, 8), "")
+	if getMaxLength() != 8 {
+		t.Fatalf("expected the limit 8, got %d", getMaxLength())
+	}
+	checkErrorCode(t, network.createAuctionWithPrice(seller, "auction1", 0, AuctionOptions{}), "")
+	checkErrorCode(t, network.createAuctionWithPrice(seller, "auction123", 0, AuctionOptions{}), ErrCodeInvalidArgument)
+
+	checkErrorCode(t, setMaxLength(admin, 256), "")
+	checkErrorCode(t, network.createAuctionWithPrice(seller, strings.Repeat("a", 256), 0, AuctionOptions{}), "")
+	checkErrorCode(t, network.createAuctionWithPrice(seller, strings.Repeat("a", 257), 0, AuctionOptions{}), ErrCodeInvalidArgument)
+
+	// 0 restores the default
+	checkErrorCode(t, setMaxLength(admin, 0), "")
+	if getMaxLength() != defaultMaxAuctionNameLength {
+		t.Fatalf("expected the default limit %d, got %d", defaultMaxAuctionNameLength, getMaxLength())
+	}
+	checkErrorCode(t, network.createAuctionWithPrice(seller, strings.Repeat("b", defaultMaxAuctionNameLength+1), 0, AuctionOptions{}), ErrCodeInvalidArgument)
+}
+
+func TestLastUpdated(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), 30)
+	lastUpdated := func() int64 {
+		t.Helper()
+		var auction Auction
+		err := json.Unmarshal(network.ledger.state[auctionKey("auction1")], &auction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return auction.LastUpdated
+	}
+
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	created := lastUpdated()
+	if created != network.ledger.time {
+		t.Fatalf("expected the time of the creation %d, got %d", network.ledger.time, created)
+	}
+
+	// Bids only write their own keys
+	network.mustBid("auction1", bid)
+	if lastUpdated() != created {
+		t.Fatal("the bid changed the time of the last update")
+	}
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	closed := lastUpdated()
+	if closed != network.ledger.time || closed <= created {
+		t.Fatalf("expected the time of the close %d, got %d", network.ledger.time, closed)
+	}
+
+	// Reveals only write their own keys, too
+	network.openBid("auction1", bid)
+	if lastUpdated() != closed {
+		t.Fatal("the reveal changed the time of the last update")
+	}
+
+	stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+	ended := lastUpdated()
+	if ended != network.ledger.time || ended <= closed {
+		t.Fatalf("expected the time of the end %d, got %d", network.ledger.time, ended)
+	}
+	var summary AuctionSummary
+	err := json.Unmarshal(stub.eventPayload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.LastUpdated != ended {
+		t.Fatalf("expected the summary to report %d, got %d", ended, summary.LastUpdated)
+	}
+}
+
+func TestMinBidPrice(t *testing.T) {
+	tests := []struct {
+		name            string
+		bidPrices       []uint64
+		wantWinner      int // Index of the winning bid, -1 for none
+		wantHammerPrice uint64
+		wantNumBidders  int
+	}{
+		{"at the floor", []uint64{20, 30}, 1, 20, 2},
+		// The bid below the floor is revealed, but it is not the second price
+		{"below the floor", []uint64{19, 30}, 1, 30, 1},
+		{"only below the floor", []uint64{19}, -1, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", AuctionOptions{MinBidPrice: 20})
+			bids := []*testBid{}
+			for _, price := range test.bidPrices {
+				bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), price)
+				network.mustBid("auction1", bid)
+				bids = append(bids, bid)
+			}
+
+			summary := network.endSealedAuction(seller, "auction1", bids)
+			checkEligibleOutcome(t, summary, bids, test.wantWinner, test.wantHammerPrice, test.wantNumBidders)
+		})
+	}
+}
+
+func TestMaxBidPrice(t *testing.T) {
+	tests := []struct {
+		name            string
+		bidPrices       []uint64
+		wantWinner      int // Index of the winning bid, -1 for none
+		wantHammerPrice uint64
+		wantNumBidders  int
+	}{
+		{"at the maximum", []uint64{100, 30}, 0, 30, 2},
+		// The bid above the maximum is revealed, but it cannot win
+		{"above the maximum", []uint64{101, 30}, 1, 30, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", AuctionOptions{MaxBidPrice: 100})
+			bids := []*testBid{}
+			for _, price := range test.bidPrices {
+				bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), price)
+				network.mustBid("auction1", bid)
+				bids = append(bids, bid)
+			}
+
+			// The highest revealed bid only considers eligible bids
+			network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.CloseAuction(ctx, "auction1", 0)
+			})
+			for _, bid := range bids {
+				network.openBid("auction1", bid)
+			}
+			var highestBid uint64
+			_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				highestBid, err = network.contract.GetHighestRevealedBid(ctx, "auction1")
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if highestBid != bids[test.wantWinner].price {
+				t.Fatalf("expected the highest revealed bid %d, got %d", bids[test.wantWinner].price, highestBid)
+			}
+
+			stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.EndAuction(ctx, "auction1", 0)
+			})
+			var summary AuctionSummary
+			err = json.Unmarshal(stub.eventPayload, &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEligibleOutcome(t, &summary, bids, test.wantWinner, test.wantHammerPrice, test.wantNumBidders)
+		})
+	}
+}
+
+// checkEligibleOutcome checks the result of an auction with a price range, ineligible bids must not appear in the counts
+func checkEligibleOutcome(t *testing.T, summary *AuctionSummary, bids []*testBid, wantWinner int, wantHammerPrice uint64, wantNumBidders int) {
+	t.Helper()
+	if summary.Status != Ended {
+		t.Fatalf("expected the auction to end, got the status %v", summary.Status)
+	}
+	if wantWinner < 0 {
+		if summary.Result.Winner != nil {
+			t.Fatal("expected no winner")
+		}
+	} else if !reflect.DeepEqual(summary.Result.Winner, bids[wantWinner].bidder.cert.Raw) {
+		t.Fatal("the wrong bidder won")
+	}
+	if summary.Result.HammerPrice != wantHammerPrice {
+		t.Fatalf("expected the hammer price %d, got %d", wantHammerPrice, summary.Result.HammerPrice)
+	}
+	if summary.NumBidders != wantNumBidders || len(summary.BidBook) != wantNumBidders {
+		t.Fatalf("expected %d eligible bidders, got %d and a bid book of %d", wantNumBidders, summary.NumBidders, len(summary.BidBook))
+	}
+}
+
+func TestListingsWithLegacyAuction(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+
+	// An auction of an earlier version of the chaincode, which still stores its two bids inline
+	legacyAuction := Auction{
+		Name:    "legacy",
+		Seller:  seller.cert.Raw,
+		Status:  AuctionStatus(Open),
+		Version: 3,
+	}
+	for _, price := range []uint64{30, 50} {
+		bid := newTestBid(t, newMockIdentity(t, "bidder", "Org1MSP"), price)
+		hiddenCommit, err := hex.DecodeString(bid.hiddenCommit(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacyAuction.LegacyBids = append(legacyAuction.LegacyBids, Bid{Buyer: bid.bidder.cert.Raw, HiddenCommit: hiddenCommit})
+	}
+	legacyAuctionJSON, err := json.Marshal(&legacyAuction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := network.ledger.newTransaction()
+	_ = setup.PutState(auctionKey("legacy"), legacyAuctionJSON)
+	err = network.ledger.commit(setup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both listings include the legacy auction, summarized from its inline bids
+	checkSummaries := func(summaries []*AuctionSummary) {
+		t.Helper()
+		numBidders := map[string]int{}
+		for _, summary := range summaries {
+			numBidders[summary.Name] = summary.NumBidders
+		}
+		if len(summaries) != 2 || numBidders["auction1"] != 0 || numBidders["legacy"] != 2 {
+			t.Fatalf("expected both auctions with 0 and 2 bidders, got %v", numBidders)
+		}
+	}
+	var listJSON string
+	_, err = network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		listJSON, err = network.contract.GetAllAuctionSummaries(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list AuctionSummaryList
+	err = json.Unmarshal([]byte(listJSON), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSummaries(list.Summaries)
+
+	_, err = network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		listJSON, err = network.contract.GetOpenAuctions(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var openSummaries []*AuctionSummary
+	err = json.Unmarshal([]byte(listJSON), &openSummaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSummaries(openSummaries)
+}
+
+func TestQueryMyLeadingAuctions(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	rival := newMockIdentity(t, "rival", "Org1MSP")
+	queryLeading := func(client *mockIdentity) []string {
+		t.Helper()
+		var summariesJSON string
+		_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			summariesJSON, err = network.contract.QueryMyLeadingAuctions(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var summaries []*AuctionSummary
+		err = json.Unmarshal([]byte(summariesJSON), &summaries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, summary := range summaries {
+			names = append(names, summary.Name)
+		}
+		return names
+	}
+	for _, auctionName := range []string{"auction1", "auction2"} {
+		network.createAuction(seller, auctionName, AuctionOptions{AuctionType: "english"})
+		_, err := network.placeBid(bidder, auctionName, 10)
+		checkErrorCode(t, err, "")
+	}
+
+	// The bidder is outbid in the second auction only
+	_, err := network.placeBid(rival, "auction2", 20)
+	checkErrorCode(t, err, "")
+	if names := queryLeading(bidder); !reflect.DeepEqual(names, []string{"auction1"}) {
+		t.Fatalf("expected the bidder to lead auction1 only, got %v", names)
+	}
+	if names := queryLeading(rival); !reflect.DeepEqual(names, []string{"auction2"}) {
+		t.Fatalf("expected the rival to lead auction2 only, got %v", names)
+	}
+
+	// Closed auctions are not listed anymore
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	if names := queryLeading(bidder); len(names) != 0 {
+		t.Fatalf("expected no leading auctions, got %v", names)
+	}
+}
+
+func TestWithdrawBid(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	other := newMockIdentity(t, "other", "Org1MSP")
+	withdraw := func(client *mockIdentity, auctionName string) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.WithdrawBid(ctx, auctionName)
+		})
+		return err
+	}
+	for _, client := range []*mockIdentity{bidder, other} {
+		network.mustSubmit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Deposit(ctx, 25)
+		})
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{MinDeposit: 10})
+	network.mustBid("auction1", newTestBid(t, bidder, 30))
+	network.mustBid("auction1", newTestBid(t, bidder, 35))
+	otherBid := newTestBid(t, other, 20)
+	network.mustBid("auction1", otherBid)
+	if network.balance(bidder) != 15 || network.balance(other) != 15 {
+		t.Fatalf("expected a deposit of 10 to be locked once per bidder, got the balances %d and %d", network.balance(bidder), network.balance(other))
+	}
+
+	// All bids of the bidder are removed and the deposit is refunded, the other bidder is not affected
+	checkErrorCode(t, withdraw(bidder, "auction1"), "")
+	if network.balance(bidder) != 25 {
+		t.Fatalf("expected the full balance of 25 after the refund, got %d", network.balance(bidder))
+	}
+	if network.balance(other) != 15 {
+		t.Fatalf("the deposit of the other bidder was touched, the balance is %d", network.balance(other))
+	}
+	keys := network.bidKeys("auction1")
+	if len(keys) != 1 {
+		t.Fatalf("expected only the bid of the other bidder to remain, got %d bids", len(keys))
+	}
+	var remainingBid Bid
+	err := json.Unmarshal(network.ledger.state[keys[0]], &remainingBid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(remainingBid.Buyer, other.cert.Raw) || hex.EncodeToString(remainingBid.HiddenCommit) != otherBid.hiddenCommit(t) {
+		t.Fatal("the bid of the other bidder was changed")
+	}
+	checkErrorCode(t, withdraw(bidder, "auction1"), ErrCodeNotFound)
+
+	// A revealed bid cannot be withdrawn anymore
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	network.openBid("auction1", otherBid)
+	checkErrorCode(t, withdraw(other, "auction1"), ErrCodeInvalidStatus)
+
+	// Neither can the open bids of an unsealed auction, which are revealed from the start
+	network.createAuction(seller, "auction2", AuctionOptions{Unsealed: true})
+	_, err = network.placeBid(bidder, "auction2", 30)
+	checkErrorCode(t, err, "")
+	checkErrorCode(t, withdraw(bidder, "auction2"), ErrCodeNotFound)
+	if len(network.bidKeys("auction2")) != 1 {
+		t.Fatal("the revealed bid was withdrawn")
+	}
+}
+
+func TestItemRefIndex(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	findAuctions := func(itemRef string) []string {
+		t.Helper()
+		var auctionNames []string
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			auctionNames, err = network.contract.FindAuctionsByItemRef(ctx, itemRef)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return auctionNames
+	}
+
+	network.createAuction(seller, "auction1", AuctionOptions{ItemRef: "item1"})
+	network.createAuction(seller, "auction2", AuctionOptions{ItemRef: "item2"})
+	if names := findAuctions("item1"); !reflect.DeepEqual(names, []string{"auction1"}) {
+		t.Fatalf("expected auction1 for item1, got %v", names)
+	}
+
+	// The same item cannot be sold twice at the same time
+	err := network.createAuctionWithPrice(seller, "auction3", 0, AuctionOptions{ItemRef: "item1"})
+	checkErrorCode(t, err, ErrCodeAlreadyExists)
+
+	// Ending the auction releases the item
+	network.endSealedAuction(seller, "auction1", nil)
+	if names := findAuctions("item1"); len(names) != 0 {
+		t.Fatalf("expected no auctions for item1 after the end, got %v", names)
+	}
+	network.createAuction(seller, "auction3", AuctionOptions{ItemRef: "item1"})
+
+	// Canceling the auction releases the item, too
+	err = network.createAuctionWithPrice(seller, "auction4", 0, AuctionOptions{ItemRef: "item2"})
+	checkErrorCode(t, err, ErrCodeAlreadyExists)
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CancelAuction(ctx, "auction2")
+	})
+	if names := findAuctions("item2"); len(names) != 0 {
+		t.Fatalf("expected no auctions for item2 after the cancellation, got %v", names)
+	}
+	network.createAuction(seller, "auction4", AuctionOptions{ItemRef: "item2"})
+}
+
+func TestAllowedMSPs(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	allowedBidder := newMockIdentity(t, "bidder1", "Org2MSP")
+	rejectedBidder := newMockIdentity(t, "bidder2", "Org3MSP")
+	err := network.createAuctionWithPrice(seller, "auction1", 100, AuctionOptions{AllowedMSPs: []string{"Org1MSP", "Org2MSP"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = network.bid("auction1", newTestBid(t, allowedBidder, 50))
+	checkErrorCode(t, err, "")
+	_, err = network.bid("auction1", newTestBid(t, rejectedBidder, 50))
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+
+	// Direct buy is restricted to the same organizations
+	_, err = network.submit(rejectedBidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction1", 100, 0)
+	})
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+	_, err = network.submit(allowedBidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction1", 100, 0)
+	})
+	checkErrorCode(t, err, "")
+}
+
+func TestComputeBidCommitment(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid := newTestBid(t, bidder, 42)
+
+	var commitment string
+	_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		commitment, err = network.contract.ComputeBidCommitment(ctx, bid.price, hex.EncodeToString(bid.salt))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The computed commitment is exactly the hidden commit which Bid stores
+	network.mustSubmit(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.Bid(ctx, "auction1", commitment)
+	})
+	keys := network.bidKeys("auction1")
+	if len(keys) != 1 {
+		t.Fatalf("expected a single bid, got %d", len(keys))
+	}
+	var storedBid Bid
+	err = json.Unmarshal(network.ledger.state[keys[0]], &storedBid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(storedBid.HiddenCommit) != commitment {
+		t.Fatalf("stored hidden commit %x differs from the computed commitment %s", storedBid.HiddenCommit, commitment)
+	}
+
+	// Salts which could not be opened later are rejected
+	_, err = network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.ComputeBidCommitment(ctx, bid.price, hex.EncodeToString(bid.salt[:minSaltLength-1]))
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected an error for a short salt")
+	}
+
+	// The bid opens with the same price and salt
+	summary := network.endSealedAuction(seller, "auction1", []*testBid{bid})
+	if summary.Result.HammerPrice != bid.price {
+		t.Fatalf("expected hammer price %d, got %d", bid.price, summary.Result.HammerPrice)
+	}
+}
+
+func TestGetCommitmentPreimageLayout(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{MinSaltBytes: 96, CommitDifficulty: 4})
+
+	var layout *CommitmentPreimageLayout
+	_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		layout, err = network.contract.GetCommitmentPreimageLayout(ctx, "auction1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.HashFunction != "SHAKE256" || layout.LeadingZeros != 4 {
+		t.Fatalf("unexpected layout: %+v", layout)
+	}
+
+	// Build the preimage only from the layout, like a client library would
+	bid := newTestBid(t, bidder, 0x0102030405060708)
+	bid.salt = append(bid.salt, make([]byte, 96-len(bid.salt))...)
+	fieldData := map[string][]byte{
+		"clientCert": bidder.cert.Raw,
+		"bidPrice":   uint64ToBigEndian(bid.price),
+		"salt":       bid.salt,
+	}
+	var preimage []byte
+	for _, field := range layout.Fields {
+		data, exists := fieldData[field.Name]
+		if !exists {
+			t.Fatalf("unknown field %q", field.Name)
+		}
+		if (field.Length != 0 && len(data) != field.Length) || len(data) < field.MinLength {
+			t.Fatalf("field %q with %d bytes does not fit length %d and minimum length %d", field.Name, len(data), field.Length, field.MinLength)
+		}
+		preimage = append(preimage, data...)
+	}
+	if layout.Fields[2].MinLength != 96 {
+		t.Fatalf("expected the minimum salt length of the auction, got %d", layout.Fields[2].MinLength)
+	}
+
+	hash := make([]byte, layout.OutputLength)
+	sha3.ShakeSum256(hash, preimage)
+	if hex.EncodeToString(hash) != bid.hiddenCommit(t) {
+		t.Fatalf("layout hash %x differs from the hidden commit %s", hash, bid.hiddenCommit(t))
+	}
+
+	_, err = network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := network.contract.GetCommitmentPreimageLayout(ctx, "missing")
+		return err
+	})
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestReopenAuction(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org1MSP")
+	closeAuction := func() {
+		t.Helper()
+		network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CloseAuction(ctx, "auction1", 0)
+		})
+	}
+	reopenAuction := func(client *mockIdentity) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.ReopenAuction(ctx, "auction1")
+		})
+		return err
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid1 := newTestBid(t, bidder1, 30)
+	network.mustBid("auction1", bid1)
+
+	// An accidental close without reveals can be reverted by the seller
+	closeAuction()
+	checkErrorCode(t, reopenAuction(bidder1), ErrCodePermissionDenied)
+	checkErrorCode(t, reopenAuction(seller), "")
+	if status := network.auctionView(seller, "auction1").Status; status != Open {
+		t.Fatalf("expected an open auction, got %v", status)
+	}
+	bid2 := newTestBid(t, bidder2, 40)
+	network.mustBid("auction1", bid2)
+
+	// After any reveal, the other bidders could adapt their bids
+	closeAuction()
+	network.openBid("auction1", bid1)
+	checkErrorCode(t, reopenAuction(seller), ErrCodeInvalidStatus)
+
+	// Ended auctions stay ended
+	network.openBid("auction1", bid2)
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+	checkErrorCode(t, reopenAuction(seller), ErrCodeInvalidStatus)
+}
+
+func TestEarliestClose(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	earliestClose := network.ledger.time + 1000
+	network.createAuction(seller, "auction1", AuctionOptions{EarliestClose: earliestClose})
+	closeAuction := func() error {
+		_, err := network.submit(seller, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.CloseAuction(ctx, "auction1", 0)
+		})
+		return err
+	}
+
+	// The seller cannot close the auction early
+	checkErrorCode(t, closeAuction(), ErrCodeInvalidStatus)
+	if status := network.auctionView(seller, "auction1").Status; status != Open {
+		t.Fatalf("expected an open auction, got %v", status)
+	}
+
+	// The next transaction takes place exactly at the earliest close time
+	network.ledger.time = earliestClose - 10
+	checkErrorCode(t, closeAuction(), "")
+	if status := network.auctionView(seller, "auction1").Status; status != Closed {
+		t.Fatalf("expected a closed auction, got %v", status)
+	}
+}
+
+func TestGetAuctionResult(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org1MSP")
+	getResult := func(auctionName string) (*AuctionResult, error) {
+		var resultJSON string
+		_, err := network.simulate(bidder1, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			resultJSON, err = network.contract.GetAuctionResult(ctx, auctionName)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		var result AuctionResult
+		err = json.Unmarshal([]byte(resultJSON), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &result, nil
+	}
+
+	// The result is only known once the auction has ended
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bids := []*testBid{newTestBid(t, bidder1, 30), newTestBid(t, bidder2, 40)}
+	for _, bid := range bids {
+		network.mustBid("auction1", bid)
+	}
+	_, err := getResult("auction1")
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+	_, err = getResult("missing")
+	checkErrorCode(t, err, ErrCodeNotFound)
+
+	// After EndAuction, the result is the one of the end event
+	summary := network.endSealedAuction(seller, "auction1", bids)
+	result, err := getResult("auction1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, summary.Result) {
+		t.Fatalf("result %+v differs from the end event result %+v", result, summary.Result)
+	}
+	if !reflect.DeepEqual(result.Winner, bidder2.cert.Raw) || result.DirectBuy || result.HammerPrice != 30 {
+		t.Fatalf("unexpected result of the sealed auction: %+v", result)
+	}
+
+	// After DirectBuy, the buyer wins at the direct buy price
+	err = network.createAuctionWithPrice(seller, "auction2", 100, AuctionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.mustSubmit(bidder2, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.DirectBuy(ctx, "auction2", 100, 0)
+	})
+	result, err = getResult("auction2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Winner, bidder2.cert.Raw) || !result.DirectBuy || result.HammerPrice != 100 {
+		t.Fatalf("unexpected result of the direct buy: %+v", result)
+	}
+}
+
+func TestGetMyBids(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org2MSP")
+	getMyBids := func(client *mockIdentity) []Bid {
+		t.Helper()
+		var bidsJSON string
+		_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			bidsJSON, err = network.contract.GetMyBids(ctx, "auction1")
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bids []Bid
+		err = json.Unmarshal([]byte(bidsJSON), &bids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bids
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid1 := newTestBid(t, bidder1, 30)
+	bid2 := newTestBid(t, bidder2, 40)
+	network.mustBid("auction1", bid1)
+	network.mustBid("auction1", bid2)
+
+	// Every buyer only sees their own bid
+	for _, bid := range []*testBid{bid1, bid2} {
+		myBids := getMyBids(bid.bidder)
+		if len(myBids) != 1 || !reflect.DeepEqual(myBids[0].Buyer, bid.bidder.cert.Raw) {
+			t.Fatalf("expected only the bid of %s, got %d bids", bid.bidder.cert.Subject.CommonName, len(myBids))
+		}
+		if hex.EncodeToString(myBids[0].HiddenCommit) != bid.hiddenCommit(t) || myBids[0].BidPrice != 0 {
+			t.Fatalf("unexpected bid of %s: %+v", bid.bidder.cert.Subject.CommonName, myBids[0])
+		}
+	}
+	if myBids := getMyBids(seller); len(myBids) != 0 {
+		t.Fatalf("expected no bids of the seller, got %d", len(myBids))
+	}
+
+	// A reveal only changes the bid of the revealing buyer
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	network.openBid("auction1", bid1)
+	if myBids := getMyBids(bidder1); myBids[0].BidPrice != 30 {
+		t.Fatalf("expected the revealed price 30, got %d", myBids[0].BidPrice)
+	}
+	if myBids := getMyBids(bidder2); myBids[0].BidPrice != 0 {
+		t.Fatalf("expected an unrevealed bid, got price %d", myBids[0].BidPrice)
+	}
+}
+
+func TestGetHighestRevealedBid(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	getHighest := func() (uint64, error) {
+		var highestBidPrice uint64
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			highestBidPrice, err = network.contract.GetHighestRevealedBid(ctx, "auction1")
+			return err
+		})
+		return highestBidPrice, err
+	}
+	checkHighest := func(want uint64) {
+		t.Helper()
+		highestBidPrice, err := getHighest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if highestBidPrice != want {
+			t.Fatalf("expected the highest revealed bid %d, got %d", want, highestBidPrice)
+		}
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{MaxBidPrice: 100})
+	bids := []*testBid{
+		newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 30),
+		newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 200),
+		newTestBid(t, newMockIdentity(t, "bidder3", "Org1MSP"), 40),
+		newTestBid(t, newMockIdentity(t, "bidder4", "Org1MSP"), 50),
+	}
+	for _, bid := range bids {
+		network.mustBid("auction1", bid)
+	}
+
+	// No bid can be revealed yet
+	_, err := getHighest()
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	// A partial reveal only counts the revealed, eligible bids
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	checkHighest(0)
+	network.openBid("auction1", bids[0])
+	checkHighest(30)
+	network.openBid("auction1", bids[1])
+	checkHighest(30)
+	network.openBid("auction1", bids[2])
+	checkHighest(40)
+
+	// After the end, all bids are revealed
+	network.openBid("auction1", bids[3])
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+	checkHighest(50)
+}
+
+func TestDepositSettlement(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	winner := newMockIdentity(t, "winner", "Org1MSP")
+	loser := newMockIdentity(t, "loser", "Org1MSP")
+	poorBidder := newMockIdentity(t, "poor", "Org1MSP")
+	deposit := func(client *mockIdentity, amount uint64) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Deposit(ctx, amount)
+		})
+		return err
+	}
+	checkErrorCode(t, deposit(winner, 0), ErrCodeInvalidArgument)
+	checkErrorCode(t, deposit(winner, 100), "")
+	checkErrorCode(t, deposit(loser, 60), "")
+	checkErrorCode(t, deposit(poorBidder, 20), "")
+	network.createAuction(seller, "auction1", AuctionOptions{MinDeposit: 50})
+
+	// Every bid locks the deposit from the balance of the bidder
+	bids := []*testBid{newTestBid(t, winner, 80), newTestBid(t, loser, 30)}
+	for _, bid := range bids {
+		network.mustBid("auction1", bid)
+	}
+	_, err := network.bid("auction1", newTestBid(t, poorBidder, 90))
+	checkErrorCode(t, err, ErrCodeInsufficientBalance)
+	if network.balance(winner) != 50 || network.balance(loser) != 10 || network.balance(poorBidder) != 20 {
+		t.Fatalf("unexpected balances after the bids: winner %d, loser %d, poor bidder %d",
+			network.balance(winner), network.balance(loser), network.balance(poorBidder))
+	}
+
+	// The winner pays the hammer price from their deposit, the rest of the deposits is refunded
+	summary := network.endSealedAuction(seller, "auction1", bids)
+	if summary.Result.HammerPrice != 30 || !reflect.DeepEqual(summary.Result.Winner, winner.cert.Raw) {
+		t.Fatalf("unexpected result: %+v", summary.Result)
+	}
+	if network.balance(winner) != 70 {
+		t.Fatalf("expected a winner balance of 70, got %d", network.balance(winner))
+	}
+	if network.balance(loser) != 60 {
+		t.Fatalf("expected a loser balance of 60, got %d", network.balance(loser))
+	}
+	if network.balance(seller) != 30 {
+		t.Fatalf("expected a seller balance of 30, got %d", network.balance(seller))
+	}
+	if len(summary.Result.Refunds) != 2 {
+		t.Fatalf("expected 2 refunds, got %d", len(summary.Result.Refunds))
+	}
+}
+
+func TestGetAuctionSeller(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	getSeller := func(auctionName string) (string, error) {
+		var sellerPem string
+		_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			sellerPem, err = network.contract.GetAuctionSeller(ctx, auctionName)
+			return err
+		})
+		return sellerPem, err
+	}
+
+	// The PEM text parses back to the certificate of the seller
+	sellerPem, err := getSeller("auction1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, rest := pem.Decode([]byte(sellerPem))
+	if block == nil || block.Type != "CERTIFICATE" || len(bytes.TrimSpace(rest)) != 0 {
+		t.Fatalf("expected a single PEM certificate, got %q", sellerPem)
+	}
+	sellerCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sellerCert.Raw, seller.cert.Raw) {
+		t.Fatal("the returned certificate is not the one of the seller")
+	}
+
+	_, err = getSeller("missing")
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestGetAuctionHistory(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	getHistory := func(auctionName string) ([]AuctionHistoryEntry, error) {
+		var historyJSON string
+		_, err := network.simulate(bidder, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			historyJSON, err = network.contract.GetAuctionHistory(ctx, auctionName)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		var history []AuctionHistoryEntry
+		err = json.Unmarshal([]byte(historyJSON), &history)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return history, nil
+	}
+
+	// Bids and reveals are stored separately, only create, close and end change the auction
+	createStub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CreateAuction(ctx, "auction1", 0, "{}")
+	})
+	bid := newTestBid(t, bidder, 30)
+	network.mustBid("auction1", bid)
+	closeStub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	network.openBid("auction1", bid)
+	endStub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.EndAuction(ctx, "auction1", 0)
+	})
+
+	// The newest state comes first
+	history, err := getHistory("auction1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEntries := []struct {
+		stub   *mockStub
+		status AuctionStatus
+	}{
+		{endStub, Ended},
+		{closeStub, Closed},
+		{createStub, Open},
+	}
+	if len(history) != len(wantEntries) {
+		t.Fatalf("expected %d history entries, got %d", len(wantEntries), len(history))
+	}
+	for i, want := range wantEntries {
+		entry := history[i]
+		if entry.TxID != want.stub.txID || entry.Timestamp != want.stub.time || entry.IsDelete {
+			t.Fatalf("entry %d is from transaction %s at %d, want %s at %d", i, entry.TxID, entry.Timestamp, want.stub.txID, want.stub.time)
+		}
+		if entry.Summary == nil || entry.Summary.Status != want.status {
+			t.Fatalf("entry %d does not have the status %v", i, want.status)
+		}
+	}
+	if history[0].Summary.Result == nil || history[0].Summary.Result.HammerPrice != 30 {
+		t.Fatal("the newest entry does not have the result")
+	}
+
+	// A cancelled auction is deleted
+	network.createAuction(seller, "auction2", AuctionOptions{})
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CancelAuction(ctx, "auction2")
+	})
+	history, err = getHistory("auction2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || !history[0].IsDelete || history[0].Summary != nil || history[1].Summary.Status != Open {
+		t.Fatalf("unexpected history of the cancelled auction: %+v", history)
+	}
+
+	_, err = getHistory("missing")
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestDirectBuyVoidsHiddenBids(t *testing.T) {
+	tests := []struct {
+		name          string
+		revealFirst   bool
+		wantNumVoided int
+	}{
+		{"open auction", false, 2},
+		{"closed auction with a reveal", true, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			buyer := newMockIdentity(t, "buyer", "Org1MSP")
+			err := network.createAuctionWithPrice(seller, "auction1", 100, AuctionOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			bids := []*testBid{
+				newTestBid(t, newMockIdentity(t, "bidder1", "Org1MSP"), 30),
+				newTestBid(t, newMockIdentity(t, "bidder2", "Org1MSP"), 40),
+			}
+			for _, bid := range bids {
+				network.mustBid("auction1", bid)
+			}
+			if test.revealFirst {
+				network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+					return network.contract.CloseAuction(ctx, "auction1", 0)
+				})
+				network.openBid("auction1", bids[0])
+			}
+
+			// The summary tells the bidders with hidden bids that they do not need to reveal them anymore
+			stub := network.mustSubmit(buyer, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.DirectBuy(ctx, "auction1", 100, 0)
+			})
+			var summary AuctionSummary
+			err = json.Unmarshal(stub.eventPayload, &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.NumVoidedBids != test.wantNumVoided {
+				t.Fatalf("expected %d voided bids, got %d", test.wantNumVoided, summary.NumVoidedBids)
+			}
+		})
+	}
+}
+
+func TestOpenBids(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid1 := newTestBid(t, bidder, 30)
+	bid2 := newTestBid(t, bidder, 50)
+	network.mustBid("auction1", bid1)
+	network.mustBid("auction1", bid2)
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+
+	// Failed reveals do not stop the others, and later reveals see the earlier ones
+	salt1 := hex.EncodeToString(bid1.salt)
+	reveals := []BidReveal{
+		{BidPrice: 30, Salt: salt1},
+		{BidPrice: 31, Salt: salt1},
+		{BidPrice: 0, Salt: salt1},
+		{BidPrice: 50, Salt: "not hex"},
+		{BidPrice: 50, Salt: hex.EncodeToString(bid2.salt[:minSaltLength-1])},
+		{BidPrice: 30, Salt: salt1},
+		{BidPrice: 50, Salt: hex.EncodeToString(bid2.salt)},
+	}
+	wantCodes := []string{"", ErrCodeBidMismatch, ErrCodeInvalidArgument, ErrCodeInvalidArgument, ErrCodeInvalidArgument, ErrCodeAlreadyRevealed, ""}
+	var results []RevealResult
+	stub := network.mustSubmit(bidder, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		results, err = network.contract.OpenBids(ctx, "auction1", reveals)
+		return err
+	})
+	if len(results) != len(reveals) {
+		t.Fatalf("expected %d results, got %d", len(reveals), len(results))
+	}
+	for i, result := range results {
+		if result.BidPrice != reveals[i].BidPrice || result.Revealed != (wantCodes[i] == "") {
+			t.Fatalf("unexpected result %d: %+v", i, result)
+		}
+		if wantCodes[i] != "" && !strings.HasPrefix(result.Error, wantCodes[i]+": ") {
+			t.Fatalf("expected result %d to fail with code %s, got %q", i, wantCodes[i], result.Error)
+		}
+		if wantCodes[i] == "" && result.Error != "" {
+			t.Fatalf("unexpected error of result %d: %s", i, result.Error)
+		}
+	}
+
+	// Both bids are revealed in the world state and covered by the reveal event
+	if stub.eventName == "" {
+		t.Fatal("expected a reveal event")
+	}
+	revealedPrices := make(map[uint64]bool)
+	for _, key := range network.bidKeys("auction1") {
+		var bid Bid
+		err := json.Unmarshal(network.ledger.state[key], &bid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revealedPrices[bid.BidPrice] = true
+	}
+	if !reflect.DeepEqual(revealedPrices, map[uint64]bool{30: true, 50: true}) {
+		t.Fatalf("expected the revealed prices 30 and 50, got %v", revealedPrices)
+	}
+}
+
+func TestVerifyMyCommitment(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	other := newMockIdentity(t, "other", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid := newTestBid(t, bidder, 30)
+	network.mustBid("auction1", bid)
+	verify := func(client *mockIdentity, bidPrice uint64, salt []byte) bool {
+		t.Helper()
+		var matches bool
+		_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			matches, err = network.contract.VerifyMyCommitment(ctx, "auction1", bidPrice, hex.EncodeToString(salt))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	// Only the exact price and salt of the client's own hidden bid match
+	otherSalt := append([]byte{}, bid.salt...)
+	otherSalt[0] ^= 1
+	tests := []struct {
+		name      string
+		client    *mockIdentity
+		bidPrice  uint64
+		salt      []byte
+		wantMatch bool
+	}{
+		{"matching", bidder, 30, bid.salt, true},
+		{"other price", bidder, 31, bid.salt, false},
+		{"other salt", bidder, 30, otherSalt, false},
+		{"other client", other, 30, bid.salt, false},
+	}
+	for _, test := range tests {
+		if matches := verify(test.client, test.bidPrice, test.salt); matches != test.wantMatch {
+			t.Fatalf("%s: expected match %v, got %v", test.name, test.wantMatch, matches)
+		}
+	}
+
+	// A revealed bid is no longer hidden
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	network.openBid("auction1", bid)
+	if verify(bidder, 30, bid.salt) {
+		t.Fatal("expected no match for a revealed bid")
+	}
+}
+
+func TestGetWinner(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder1 := newMockIdentity(t, "bidder1", "Org1MSP")
+	bidder2 := newMockIdentity(t, "bidder2", "Org1MSP")
+	getWinner := func(auctionName string) (*WinnerInfo, error) {
+		var winner *WinnerInfo
+		_, err := network.simulate(bidder1, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			winner, err = network.contract.GetWinner(ctx, auctionName)
+			return err
+		})
+		return winner, err
+	}
+
+	// There is no winner before the end
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bids := []*testBid{newTestBid(t, bidder1, 30), newTestBid(t, bidder2, 40)}
+	for _, bid := range bids {
+		network.mustBid("auction1", bid)
+	}
+	_, err := getWinner("auction1")
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	// The highest bidder wins at the second price
+	network.endSealedAuction(seller, "auction1", bids)
+	winner, err := getWinner("auction1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(certPemToDer(winner.WinnerPem), bidder2.cert.Raw) || winner.DirectBuy || winner.HammerPrice != 30 {
+		t.Fatalf("unexpected winner: %+v", winner)
+	}
+
+	// An auction whose reserve price is not met ends without a winner
+	network.createAuction(seller, "auction2", AuctionOptions{HasReserve: true, ReservePrice: 100})
+	bid := newTestBid(t, bidder1, 50)
+	network.mustBid("auction2", bid)
+	network.endSealedAuction(seller, "auction2", []*testBid{bid})
+	_, err = getWinner("auction2")
+	checkErrorCode(t, err, ErrCodeNotFound)
+
+	_, err = getWinner("missing")
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestDutchAccept(t *testing.T) {
+	withReserve := AuctionOptions{AuctionType: "dutch", StartPrice: 100, PriceDecrement: 15, DecrementInterval: 60, HasReserve: true, ReservePrice: 50}
+	withoutReserve := withReserve
+	withoutReserve.HasReserve = false
+	withoutReserve.ReservePrice = 0
+	tests := []struct {
+		name      string
+		options   AuctionOptions
+		elapsed   int64 // Seconds since the clock started
+		wantCode  string
+		wantPrice uint64
+	}{
+		{"at the start", withReserve, 0, "", 100},
+		{"within the first interval", withReserve, 59, "", 100},
+		{"after one interval", withReserve, 60, "", 85},
+		{"last price above the reserve", withReserve, 180, "", 55},
+		{"at the reserve floor", withReserve, 240, "", 50},
+		{"long after reaching the floor", withReserve, 100000, "", 50},
+		{"run down to zero", withoutReserve, 420, ErrCodeInvalidStatus, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			buyer := newMockIdentity(t, "buyer", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+			clockStart := network.ledger.time
+
+			// The next transaction takes place the given time after the creation
+			network.ledger.time = clockStart + test.elapsed - 10
+			stub, err := network.submit(buyer, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.Accept(ctx, "auction1")
+			})
+			checkErrorCode(t, err, test.wantCode)
+			if err != nil {
+				return
+			}
+			var summary AuctionSummary
+			err = json.Unmarshal(stub.eventPayload, &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Status != Ended || summary.Result.HammerPrice != test.wantPrice || !reflect.DeepEqual(summary.Result.Winner, buyer.cert.Raw) {
+				t.Fatalf("unexpected result: status %v, hammer price %d", summary.Status, summary.Result.HammerPrice)
+			}
+
+			// Only the first accepting transaction wins
+			_, err = network.submit(newMockIdentity(t, "late", "Org1MSP"), func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.Accept(ctx, "auction1")
+			})
+			checkErrorCode(t, err, ErrCodeInvalidStatus)
+		})
+	}
+}
+
+func TestGetBidCommitment(t *testing.T) {
+	network := newTestNetwork(t)
+	admin := newMockIdentity(t, "admin", "Org1MSP")
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	bidder := newMockIdentity(t, "bidder", "Org1MSP")
+	noBidder := newMockIdentity(t, "nobidder", "Org1MSP")
+	network.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.InitLedger(ctx)
+	})
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	bid := newTestBid(t, bidder, 30)
+	network.mustBid("auction1", bid)
+	getCommitment := func(client *mockIdentity, buyerPem string) (string, error) {
+		var commitment string
+		_, err := network.simulate(client, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			commitment, err = network.contract.GetBidCommitment(ctx, "auction1", buyerPem)
+			return err
+		})
+		return commitment, err
+	}
+	bidderPem := *certDerToPem(bidder.cert.Raw)
+
+	// Nobody can query a commitment while bidding is open
+	_, err := getCommitment(seller, bidderPem)
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+
+	network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.CloseAuction(ctx, "auction1", 0)
+	})
+	tests := []struct {
+		name     string
+		client   *mockIdentity
+		buyerPem string
+		wantCode string
+	}{
+		{"seller", seller, bidderPem, ""},
+		{"admin", admin, bidderPem, ""},
+		{"bidder", bidder, bidderPem, ErrCodePermissionDenied},
+		{"buyer without bid", seller, *certDerToPem(noBidder.cert.Raw), ErrCodeNotFound},
+		{"invalid certificate", seller, "not a certificate", ErrCodeInvalidArgument},
+	}
+	for _, test := range tests {
+		commitment, err := getCommitment(test.client, test.buyerPem)
+		checkErrorCode(t, err, test.wantCode)
+		if err == nil && commitment != bid.hiddenCommit(t) {
+			t.Fatalf("%s: expected the hidden commit of the bid, got %s", test.name, commitment)
+		}
+	}
+
+	// A revealed bid has no hidden commitment left to audit
+	network.openBid("auction1", bid)
+	_, err = getCommitment(seller, bidderPem)
+	checkErrorCode(t, err, ErrCodeNotFound)
+}
+
+func TestPreviewResultMatchesEndAuction(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    AuctionOptions
+		prices     []uint64
+		wantNumTie int // Number of tied bidders in the preview
+	}{
+		{"second price", AuctionOptions{}, []uint64{10, 40, 20}, 0},
+		{"first price", AuctionOptions{AuctionType: "firstPrice"}, []uint64{10, 40, 20}, 0},
+		{"reserve price", AuctionOptions{HasReserve: true, ReservePrice: 25}, []uint64{10, 40, 20}, 0},
+		{"two units", AuctionOptions{Quantity: 2}, []uint64{10, 40, 20}, 0},
+		{"all-pay", AuctionOptions{AuctionType: "allPay"}, []uint64{10, 40, 20}, 0},
+		{"tie for the last unit", AuctionOptions{Quantity: 2}, []uint64{40, 30, 30, 10}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := newTestNetwork(t)
+			seller := newMockIdentity(t, "seller", "Org1MSP")
+			network.createAuction(seller, "auction1", test.options)
+			bids := []*testBid{}
+			for i, price := range test.prices {
+				bid := newTestBid(t, newMockIdentity(t, fmt.Sprintf("bidder%d", i), "Org1MSP"), price)
+				network.mustBid("auction1", bid)
+				bids = append(bids, bid)
+			}
+			network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.CloseAuction(ctx, "auction1", 0)
+			})
+			for _, bid := range bids {
+				network.openBid("auction1", bid)
+			}
+
+			var previewJSON string
+			_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				previewJSON, err = network.contract.PreviewResult(ctx, "auction1")
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var preview ResultPreview
+			err = json.Unmarshal([]byte(previewJSON), &preview)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stub := network.mustSubmit(seller, func(ctx contractapi.TransactionContextInterface) error {
+				return network.contract.EndAuction(ctx, "auction1", 0)
+			})
+			var summary AuctionSummary
+			err = json.Unmarshal(stub.eventPayload, &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(preview.TiedBidders) != test.wantNumTie {
+				t.Fatalf("expected %d tied bidders, got %d", test.wantNumTie, len(preview.TiedBidders))
+			}
+			if test.wantNumTie == 0 {
+				// Without a tie, the preview is exactly the result
+				if !reflect.DeepEqual(preview.Result, summary.Result) {
+					t.Fatalf("preview %+v differs from the result %+v", preview.Result, summary.Result)
+				}
+				return
+			}
+
+			// With a tie, the sure winners win and the tied units go to tied bidders
+			if preview.Result.HammerPrice != summary.Result.HammerPrice {
+				t.Fatalf("preview hammer price %d differs from %d", preview.Result.HammerPrice, summary.Result.HammerPrice)
+			}
+			for _, winner := range preview.Result.Winners {
+				if !isAuctionWinner(&Auction{Result: summary.Result}, winner) {
+					t.Fatal("a sure winner of the preview did not win")
+				}
+			}
+			numTiedWinners := 0
+			for _, tiedBidder := range preview.TiedBidders {
+				if isAuctionWinner(&Auction{Result: summary.Result}, tiedBidder) {
+					numTiedWinners += 1
+				}
+			}
+			if uint64(numTiedWinners) != preview.NumTiedUnits || len(summary.Result.Winners) != len(preview.Result.Winners)+numTiedWinners {
+				t.Fatalf("expected %d tied winners besides %d sure winners, got %d winners",
+					preview.NumTiedUnits, len(preview.Result.Winners), len(summary.Result.Winners))
+			}
+		})
+	}
+}
+
+func TestGetBiddingVelocity(t *testing.T) {
+	network := newTestNetwork(t)
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	network.createAuction(seller, "auction1", AuctionOptions{})
+	getVelocity := func(bucketSeconds int64) ([]BidBucket, error) {
+		var buckets []BidBucket
+		_, err := network.simulate(seller, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			buckets, err = network.contract.GetBiddingVelocity(ctx, "auction1", bucketSeconds)
+			return err
+		})
+		return buckets, err
+	}
+
+	// Submit bids at the given times, two of them in the same bucket
+	bids := []*testBid{}
+	for i, bidTime := range []int64{1700000200, 1700000210, 1700000350, 1700000990} {
+		bid := newTestBid(t, newMockIdentity(t, fmt.Sprintf("bidder%d", i), "Org1MSP"), 10)
+		network.ledger.time = bidTime - 10
+		network.mustBid("auction1", bid)
+		bids = append(bids, bid)
+	}
+
+	buckets, err := getVelocity(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBuckets := []BidBucket{
+		{Start: 1700000200, NumBids: 2},
+		{Start: 1700000300, NumBids: 1},
+		{Start: 1700000900, NumBids: 1},
+	}
+	if !reflect.DeepEqual(buckets, wantBuckets) {
+		t.Fatalf("got buckets %+v, want %+v", buckets, wantBuckets)
+	}
+
+	// A single bucket holds all bids
+	buckets, err = getVelocity(1000000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].NumBids != len(bids) {
+		t.Fatalf("expected one bucket with %d bids, got %+v", len(bids), buckets)
+	}
+
+	_, err = getVelocity(0)
+	checkErrorCode(t, err, ErrCodeInvalidArgument)
+
+	// The velocity is not available after the end
+	network.endSealedAuction(seller, "auction1", bids)
+	_, err = getVelocity(100)
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+}
+
+func TestAuctionFees(t *testing.T) {
+	network := newTestNetwork(t)
+	admin := newMockIdentity(t, "admin", "Org1MSP")
+	platform := newMockIdentity(t, "platform", "Org1MSP")
+	seller := newMockIdentity(t, "seller", "Org1MSP")
+	winner := newMockIdentity(t, "winner", "Org1MSP")
+	loser := newMockIdentity(t, "loser", "Org1MSP")
+	network.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return network.contract.InitLedger(ctx)
+	})
+	setFeeRecipient := func(client *mockIdentity) error {
+		_, err := network.submit(client, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.SetFeeRecipient(ctx, *certDerToPem(platform.cert.Raw))
+		})
+		return err
+	}
+
+	// Fees need a recipient, which only the admin can configure
+	err := network.createAuctionWithPrice(seller, "auction1", 0, AuctionOptions{MinDeposit: 50, FeeBps: 1000})
+	checkErrorCode(t, err, ErrCodeInvalidStatus)
+	checkErrorCode(t, setFeeRecipient(seller), ErrCodePermissionDenied)
+	checkErrorCode(t, setFeeRecipient(admin), "")
+
+	invalidOptions := []AuctionOptions{
+		{MinDeposit: 50, FeeBps: maxFeeBps + 1},
+		{MinDeposit: 50, FeeBps: 1000, FeePayer: "platform"},
+		{FeeBps: 1000},
+	}
+	for _, options := range invalidOptions {
+		err = network.createAuctionWithPrice(seller, "auction1", 0, options)
+		checkErrorCode(t, err, ErrCodeInvalidArgument)
+	}
+	network.createAuction(seller, "auction1", AuctionOptions{MinDeposit: 50, FeeBps: 1000, FeePayer: "buyer"})
+
+	// The fee recipient cannot bid
+	_, err = network.bid("auction1", newTestBid(t, platform, 40))
+	checkErrorCode(t, err, ErrCodePermissionDenied)
+
+	// The winner pays the hammer price and the fee on top
+	bids := []*testBid{newTestBid(t, winner, 80), newTestBid(t, loser, 30)}
+	for _, bid := range bids {
+		network.mustSubmit(bid.bidder, func(ctx contractapi.TransactionContextInterface) error {
+			return network.contract.Deposit(ctx, 50)
+		})
+		network.mustBid("auction1", bid)
+	}
+	summary := network.endSealedAuction(seller, "auction1", bids)
+	if summary.Result.HammerPrice != 30 || summary.Result.Fee != 3 {
+		t.Fatalf("expected a hammer price of 30 and a fee of 3, got %+v", summary.Result)
+	}
+	if network.balance(winner) != 17 || network.balance(loser) != 50 || network.balance(seller) != 30 || network.balance(platform) != 3 {
+		t.Fatalf("unexpected balances: winner %d, loser %d, seller %d, platform %d",
+			network.balance(winner), network.balance(loser), network.balance(seller), network.balance(platform))
+	}
+}
@@ -0,0 +1,366 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// mockLedger is an in-memory world state which validates transactions like a LevelDB peer
+// Every key has the number of the commit which wrote it last as its version.
+type mockLedger struct {
+	state      map[string][]byte
+	versions   map[string]int
+	policies   map[string][]byte
+	history    map[string][]*queryresult.KeyModification // Oldest modification first
+	numTxs     int
+	numCommits int
+	time       int64
+}
+
+func newMockLedger() *mockLedger {
+	return &mockLedger{
+		state:    make(map[string][]byte),
+		versions: make(map[string]int),
+		policies: make(map[string][]byte),
+		history:  make(map[string][]*queryresult.KeyModification),
+		time:     1700000000,
+	}
+}
+
+// rangeKeys returns the keys from startKey (inclusive) to endKey (exclusive) in lexical order
+func (ledger *mockLedger) rangeKeys(startKey string, endKey string) []string {
+	keys := []string{}
+	for key := range ledger.state {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newTransaction starts the simulation of a transaction on the current world state
+// The transaction time advances by 10 seconds per transaction.
+func (ledger *mockLedger) newTransaction() *mockStub {
+	ledger.numTxs += 1
+	ledger.time += 10
+	return &mockStub{
+		ledger:   ledger,
+		txID:     fmt.Sprintf("tx%d", ledger.numTxs),
+		time:     ledger.time,
+		reads:    make(map[string]int),
+		writes:   make(map[string][]byte),
+		policies: make(map[string][]byte),
+	}
+}
+
+// commit validates the read set of the transaction against the current world state and applies its writes
+// Like a peer, it fails with an MVCC read conflict if a read key has changed,
+// and with a phantom read conflict if the keys of a range read have changed.
+func (ledger *mockLedger) commit(stub *mockStub) error {
+	for key, version := range stub.reads {
+		if ledger.versions[key] != version {
+			return fmt.Errorf("MVCC_READ_CONFLICT on key %q", key)
+		}
+	}
+	for _, read := range stub.rangeReads {
+		if strings.Join(ledger.rangeKeys(read.startKey, read.endKey), "|") != strings.Join(read.keys, "|") {
+			return fmt.Errorf("PHANTOM_READ_CONFLICT on range %q", read.startKey)
+		}
+	}
+	ledger.numCommits += 1
+	for key, value := range stub.writes {
+		ledger.history[key] = append(ledger.history[key], &queryresult.KeyModification{
+			TxId:      stub.txID,
+			Value:     value,
+			Timestamp: &timestamp.Timestamp{Seconds: stub.time},
+			IsDelete:  value == nil,
+		})
+		if value == nil {
+			delete(ledger.state, key)
+			delete(ledger.versions, key)
+			continue
+		}
+		ledger.state[key] = value
+		ledger.versions[key] = ledger.numCommits
+	}
+	for key, policy := range stub.policies {
+		ledger.policies[key] = policy
+	}
+	return nil
+}
+
+// rangeRead is a range query of a transaction with the keys it returned
+type rangeRead struct {
+	startKey string
+	endKey   string
+	keys     []string
+}
+
+// mockStub simulates a single transaction on a mockLedger
+// Like the peer, it does not return the transaction's own writes when reading.
+type mockStub struct {
+	shim.ChaincodeStubInterface
+	ledger       *mockLedger
+	txID         string
+	time         int64
+	reads        map[string]int
+	rangeReads   []rangeRead
+	writes       map[string][]byte // A nil value deletes the key
+	policies     map[string][]byte
+	eventName    string
+	eventPayload []byte
+	numGetState  int
+}
+
+func (stub *mockStub) GetTxID() string {
+	return stub.txID
+}
+
+func (stub *mockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: stub.time}, nil
+}
+
+func (stub *mockStub) GetState(key string) ([]byte, error) {
+	stub.numGetState += 1
+	stub.reads[key] = stub.ledger.versions[key]
+	return stub.ledger.state[key], nil
+}
+
+func (stub *mockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	stub.writes[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (stub *mockStub) DelState(key string) error {
+	stub.writes[key] = nil
+	return nil
+}
+
+func (stub *mockStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return stub.ledger.policies[key], nil
+}
+
+func (stub *mockStub) SetStateValidationParameter(key string, policy []byte) error {
+	stub.policies[key] = policy
+	return nil
+}
+
+func (stub *mockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func (stub *mockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(compositeKey, "\x00"), "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (stub *mockStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	keys := stub.ledger.rangeKeys(startKey, endKey)
+	stub.rangeReads = append(stub.rangeReads, rangeRead{startKey, endKey, keys})
+	iterator := &mockIterator{}
+	for _, key := range keys {
+		iterator.entries = append(iterator.entries, &queryresult.KV{Key: key, Value: stub.ledger.state[key]})
+	}
+	return iterator, nil
+}
+
+func (stub *mockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return stub.GetStateByRange(startKey, startKey+string(rune(0x10FFFF)))
+}
+
+// GetQueryResult fails like on a LevelDB peer, so the contract falls back to range reads
+func (stub *mockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("ExecuteQuery not supported for leveldb")
+}
+
+// GetHistoryForKey returns the committed modifications of the key newest first, like a Fabric 2.x peer
+func (stub *mockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := stub.ledger.history[key]
+	entries := make([]*queryresult.KeyModification, len(modifications))
+	for i, modification := range modifications {
+		entries[len(modifications)-1-i] = modification
+	}
+	return &mockHistoryIterator{entries: entries}, nil
+}
+
+func (stub *mockStub) SetEvent(name string, payload []byte) error {
+	stub.eventName = name
+	stub.eventPayload = payload
+	return nil
+}
+
+// mockIterator iterates over the result of a range read
+type mockIterator struct {
+	entries []*queryresult.KV
+	next    int
+}
+
+func (iterator *mockIterator) HasNext() bool {
+	return iterator.next < len(iterator.entries)
+}
+
+func (iterator *mockIterator) Next() (*queryresult.KV, error) {
+	if !iterator.HasNext() {
+		return nil, fmt.Errorf("no more entries")
+	}
+	iterator.next += 1
+	return iterator.entries[iterator.next-1], nil
+}
+
+func (iterator *mockIterator) Close() error {
+	return nil
+}
+
+// mockHistoryIterator iterates over the modifications of a key
+type mockHistoryIterator struct {
+	entries []*queryresult.KeyModification
+	next    int
+}
+
+func (iterator *mockHistoryIterator) HasNext() bool {
+	return iterator.next < len(iterator.entries)
+}
+
+func (iterator *mockHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !iterator.HasNext() {
+		return nil, fmt.Errorf("no more entries")
+	}
+	iterator.next += 1
+	return iterator.entries[iterator.next-1], nil
+}
+
+func (iterator *mockHistoryIterator) Close() error {
+	return nil
+}
+
+// mockIdentity is a client with a self-signed certificate and its private key
+type mockIdentity struct {
+	cid.ClientIdentity
+	cert  *x509.Certificate
+	key   *ecdsa.PrivateKey
+	mspID string
+}
+
+func newMockIdentity(t *testing.T, name string, mspID string) *mockIdentity {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(certDer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mockIdentity{cert: cert, key: key, mspID: mspID}
+}
+
+func (identity *mockIdentity) GetID() (string, error) {
+	return identity.cert.Subject.CommonName, nil
+}
+
+func (identity *mockIdentity) GetMSPID() (string, error) {
+	return identity.mspID, nil
+}
+
+func (identity *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return identity.cert, nil
+}
+
+// testNetwork runs transactions of the auction contract on a mockLedger
+type testNetwork struct {
+	t        *testing.T
+	ledger   *mockLedger
+	contract *VickreyAuctionContract
+}
+
+func newTestNetwork(t *testing.T) *testNetwork {
+	return &testNetwork{t: t, ledger: newMockLedger(), contract: new(VickreyAuctionContract)}
+}
+
+// simulate runs a transaction of the client like an endorsing peer, without committing it
+func (network *testNetwork) simulate(client *mockIdentity, transaction func(ctx contractapi.TransactionContextInterface) error) (*mockStub, error) {
+	stub := network.ledger.newTransaction()
+	ctx := new(AuctionTransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(client)
+	return stub, transaction(ctx)
+}
+
+// submit simulates a transaction of the client and commits it if the simulation succeeded
+func (network *testNetwork) submit(client *mockIdentity, transaction func(ctx contractapi.TransactionContextInterface) error) (*mockStub, error) {
+	stub, err := network.simulate(client, transaction)
+	if err != nil {
+		return stub, err
+	}
+	return stub, network.ledger.commit(stub)
+}
+
+// balance returns the committed balance of the client
+func (network *testNetwork) balance(client *mockIdentity) uint64 {
+	network.t.Helper()
+	balanceJSON := network.ledger.state[balanceKey(certFingerprint(client.cert.Raw))]
+	if balanceJSON == nil {
+		return 0
+	}
+	var balance uint64
+	err := json.Unmarshal(balanceJSON, &balance)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	return balance
+}
+
+// mustSubmit submits a transaction which must succeed
+func (network *testNetwork) mustSubmit(client *mockIdentity, transaction func(ctx contractapi.TransactionContextInterface) error) *mockStub {
+	network.t.Helper()
+	stub, err := network.submit(client, transaction)
+	if err != nil {
+		network.t.Fatal(err)
+	}
+	return stub
+}
@@ -5,7 +5,10 @@ SPDX-License-Identifier: Apache-2.0
 package auction
 
 import (
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 
@@ -20,6 +23,24 @@ func getSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (*
 	return cert, nil
 }
 
+// checkClientMSPAllowed returns an error if the submitting client is not a member of one of the allowed MSPs
+// An empty allow list allows clients from all MSPs.
+func checkClientMSPAllowed(ctx contractapi.TransactionContextInterface, allowedMSPs []string) error {
+	if len(allowedMSPs) == 0 {
+		return nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to read client MSP ID: %v", err)
+	}
+	for _, allowedMSP := range allowedMSPs {
+		if mspID == allowedMSP {
+			return nil
+		}
+	}
+	return newAuctionError(ErrCodePermissionDenied, "clients from MSP %s are not allowed to participate in this auction", mspID)
+}
+
 // certDerToPem converts a certificate from binary DER to PEM text format
 func certDerToPem(derCert []byte) *string {
 	pemCertBytes := pem.EncodeToMemory(&pem.Block{
@@ -34,20 +55,41 @@ func certDerToPem(derCert []byte) *string {
 	return &pemCert
 }
 
+// certPemString converts a certificate from binary DER to PEM text format, it returns an empty string on failure
+func certPemString(derCert []byte) string {
+	pemCert := certDerToPem(derCert)
+	if pemCert == nil {
+		return ""
+	}
+	return *pemCert
+}
+
 // certPemToDer converts a certificate from PEM text to binary DER format
+// Bidders, sellers and admins are identified by the exact DER bytes of their certificate, as the client identity provides them.
+// Therefore the certificate must be strict DER without trailing data, so that it matches the stored one,
+// however the PEM text is formatted. It returns nil if the text is not a valid certificate.
 func certPemToDer(pemCert string) []byte {
 	block, _ := pem.Decode([]byte(pemCert))
-	if block == nil {
+	if block == nil || block.Type != "CERTIFICATE" {
 		return nil
 	}
-	return block.Bytes
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	return cert.Raw
+}
+
+// certFingerprint computes the hex encoded SHA-256 fingerprint of a DER certificate
+func certFingerprint(derCert []byte) string {
+	fingerprint := sha256.Sum256(derCert)
+	return hex.EncodeToString(fingerprint[:])
 }
 
-func intToByteArray(val int) []byte {
-	arr := make([]byte, 4)
-	arr[0] = byte(val)
-	arr[1] = byte(val >> 8)
-	arr[2] = byte(val >> 16)
-	arr[3] = byte(val >> 24)
+// uint64ToBigEndian encodes a number as 8 bytes in big-endian order
+// This is the only integer encoding of the bid commitment scheme, clients must encode the bid price the same way.
+func uint64ToBigEndian(val uint64) []byte {
+	arr := make([]byte, 8)
+	binary.BigEndian.PutUint64(arr, val)
 	return arr
 }
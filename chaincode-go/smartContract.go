@@ -12,7 +12,10 @@ import (
 )
 
 func main() {
-	auctionSmartContract, err := contractapi.NewChaincode(&auction.VickreyAuctionContract{})
+	auctionContract := new(auction.VickreyAuctionContract)
+	auctionContract.TransactionContextHandler = new(auction.AuctionTransactionContext)
+
+	auctionSmartContract, err := contractapi.NewChaincode(auctionContract)
 	if err != nil {
 		log.Panicf("Error creating auction chaincode: %v", err)
 	}